@@ -31,52 +31,64 @@ import (
 	"github.com/clusterpedia-io/client-go/constants"
 )
 
+// The defaults of the clients, see Option.
 const (
 	DefaultQPS            float32 = 2000
 	DefaultBurst          int     = 2000
 	DefaultTimeoutSeconds         = 10
 )
 
-func Client() (client.Client, error) {
+// Client returns the controller-runtime client of the clusterpedia resources,
+// the config is loaded by controller-runtime, e.g. from the --kubeconfig flag.
+func Client(opts ...Option) (client.Client, error) {
 	restConfig, err := ctrl.GetConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	return newClient(restConfig)
+	return New(restConfig, opts...)
 }
 
-func ClusterClient(cluster string) (client.Client, error) {
+// ClusterClient returns the controller-runtime client of the resources of the cluster like Client.
+func ClusterClient(cluster string, opts ...Option) (client.Client, error) {
 	restConfig, err := ctrl.GetConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	return newClient(restConfig, cluster)
+	return New(restConfig, append(opts[:len(opts):len(opts)], WithCluster(cluster))...)
 }
 
 func GetClient(restConfig *rest.Config, cluster ...string) (client.Client, error) {
-	return newClient(restConfig, cluster...)
+	if len(cluster) == 1 {
+		return New(restConfig, WithCluster(cluster[0]))
+	}
+	return New(restConfig)
 }
 
-func newClient(restConfig *rest.Config, cluster ...string) (client.Client, error) {
-	var err error
-
-	if len(cluster) == 1 {
-		restConfig, err = ClusterConfigFor(restConfig, cluster[0])
-	} else {
-		restConfig, err = ConfigFor(restConfig)
+// New returns the controller-runtime client of the clusterpedia resources, the default scheme contains
+// the types of client-go and the clusterpedia cluster API.
+func New(restConfig *rest.Config, opts ...Option) (client.Client, error) {
+	options := NewOptions(opts...)
+	restConfig, err := options.ConfigFor(restConfig)
+	if err != nil {
+		return nil, err
 	}
+	httpClient, err := options.HTTPClientFor(restConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	scheme := runtime.NewScheme()
-	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
-	utilruntime.Must(clusterv1alpha2.AddToScheme(scheme))
+	scheme := options.Scheme
+	if scheme == nil {
+		scheme = runtime.NewScheme()
+		utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+		utilruntime.Must(clusterv1alpha2.AddToScheme(scheme))
+	}
 
 	c, err := client.New(restConfig, client.Options{
-		Scheme: scheme,
+		Scheme:     scheme,
+		HTTPClient: httpClient,
 	})
 	if err != nil {
 		return nil, err
@@ -85,47 +97,61 @@ func newClient(restConfig *rest.Config, cluster ...string) (client.Client, error
 	return c, nil
 }
 
+// ConfigFor returns a copy of cfg whose host is the path of the clusterpedia resources,
+// the path of cfg.Host is kept as the prefix, e.g. the path of an ingress.
+// The resources path composed in cfg.Host is detected, so ConfigFor(ConfigFor(cfg)) is the same as ConfigFor(cfg).
 func ConfigFor(cfg *rest.Config) (*rest.Config, error) {
+	return ConfigForBasePath(cfg, constants.ClusterPediaAPIPath)
+}
+
+// ConfigForBasePath is like ConfigFor, basePath is the path of the resources served by clusterpedia,
+// e.g. the path of a standalone clusterpedia apiserver behind a proxy.
+func ConfigForBasePath(cfg *rest.Config, basePath string) (*rest.Config, error) {
 	configShallowCopy := *cfg
 
-	// reset clusterpedia api path
-	if err := SetConfigDefaults(&configShallowCopy); err != nil {
+	host, err := resourcesHost(configShallowCopy.Host, basePath)
+	if err != nil {
 		return nil, err
 	}
+	configShallowCopy.Host = host
+	setConfigDefaults(&configShallowCopy)
 
 	return &configShallowCopy, nil
 }
 
+// ClusterConfigFor returns a copy of cfg whose host is the path of the resources of the cluster like ConfigFor,
+// the path of another cluster composed in cfg.Host is replaced.
 func ClusterConfigFor(cfg *rest.Config, cluster string) (*rest.Config, error) {
-	configShallowCopy, err := ConfigFor(cfg)
-	if err != nil {
-		return nil, err
-	}
-	configShallowCopy.Host += constants.ClusterAPIPath + cluster
-	return configShallowCopy, nil
+	return ClusterConfigForBasePath(cfg, constants.ClusterPediaAPIPath, cluster)
 }
 
-func NewForConfig(cfg *rest.Config) (kubernetes.Interface, error) {
-	clientConfig, err := ConfigFor(cfg)
-	if err != nil {
-		return nil, err
-	}
+// ClusterConfigForBasePath is like ClusterConfigFor with the basePath of ConfigForBasePath.
+func ClusterConfigForBasePath(cfg *rest.Config, basePath, cluster string) (*rest.Config, error) {
+	configShallowCopy := *cfg
 
-	kubeClient, err := kubernetes.NewForConfig(clientConfig)
+	host, err := clusterResourcesHost(configShallowCopy.Host, basePath, cluster)
 	if err != nil {
 		return nil, err
 	}
+	configShallowCopy.Host = host
+	setConfigDefaults(&configShallowCopy)
 
-	return kubeClient, nil
+	return &configShallowCopy, nil
 }
 
-func NewClusterForConfig(cfg *rest.Config, cluster string) (kubernetes.Interface, error) {
-	clientConfig, err := ClusterConfigFor(cfg, cluster)
+// NewForConfig returns the kubernetes client of the clusterpedia resources.
+func NewForConfig(cfg *rest.Config, opts ...Option) (kubernetes.Interface, error) {
+	options := NewOptions(opts...)
+	clientConfig, err := options.ConfigFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := options.HTTPClientFor(clientConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	kubeClient, err := kubernetes.NewForConfig(clientConfig)
+	kubeClient, err := kubernetes.NewForConfigAndClient(clientConfig, httpClient)
 	if err != nil {
 		return nil, err
 	}
@@ -133,8 +159,24 @@ func NewClusterForConfig(cfg *rest.Config, cluster string) (kubernetes.Interface
 	return kubeClient, nil
 }
 
+// NewClusterForConfig returns the kubernetes client of the resources of the cluster.
+func NewClusterForConfig(cfg *rest.Config, cluster string, opts ...Option) (kubernetes.Interface, error) {
+	return NewForConfig(cfg, append(opts[:len(opts):len(opts)], WithCluster(cluster))...)
+}
+
+// SetConfigDefaults sets the host to the path of the clusterpedia resources like ConfigFor,
+// and sets the default timeout, QPS, burst and user agent.
 func SetConfigDefaults(config *rest.Config) error {
-	config.Host += constants.ClusterPediaAPIPath
+	host, err := resourcesHost(config.Host, constants.ClusterPediaAPIPath)
+	if err != nil {
+		return err
+	}
+	config.Host = host
+	setConfigDefaults(config)
+	return nil
+}
+
+func setConfigDefaults(config *rest.Config) {
 	if config.Timeout == 0 {
 		config.Timeout = DefaultTimeoutSeconds * time.Second
 	}
@@ -147,6 +189,4 @@ func SetConfigDefaults(config *rest.Config) error {
 	if config.UserAgent == "" {
 		config.UserAgent = rest.DefaultKubernetesUserAgent()
 	}
-
-	return nil
 }
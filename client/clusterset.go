@@ -0,0 +1,116 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net/http"
+
+	"github.com/clusterpedia-io/client-go/pkg/lru"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// DefaultClusterCacheSize is the number of the cluster clients cached by a ClusterSet by default.
+const DefaultClusterCacheSize = 100
+
+// ClusterSet is the client of the resources aggregated from all of the clusters,
+// Cluster returns the client of a single cluster.
+//
+// The clients of all clusters share the http.Client and the rate limiter of the ClusterSet, so the connections
+// are reused across the clusters and the QPS is the QPS of the ClusterSet, the least recently used
+// cluster clients are evicted when there are more clusters than the cache size.
+type ClusterSet struct {
+	kubernetes.Interface
+
+	config   *rest.Config
+	options  *Options
+	clusters *lru.Cache[string, kubernetes.Interface]
+}
+
+// NewClusterSetForConfig creates a ClusterSet, size bounds the number of cached cluster clients,
+// a size <= 0 uses DefaultClusterCacheSize. The options are applied to the clients of all clusters,
+// except the cluster option.
+func NewClusterSetForConfig(cfg *rest.Config, size int, opts ...Option) (*ClusterSet, error) {
+	options := NewOptions(opts...)
+	options.Cluster = ""
+
+	config, err := options.ConfigFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// the clients of the clusters share the rate limiter, so the clusters don't multiply the QPS
+	cfg = rest.CopyConfig(cfg)
+	cfg.RateLimiter = SharedRateLimiter(config)
+	config.RateLimiter = cfg.RateLimiter
+	if options.HTTPClient, err = options.HTTPClientFor(config); err != nil {
+		return nil, err
+	}
+	kubeClient, err := kubernetes.NewForConfigAndClient(config, options.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if size <= 0 {
+		size = DefaultClusterCacheSize
+	}
+	return &ClusterSet{
+		Interface: kubeClient,
+		config:    cfg,
+		options:   options,
+		clusters:  lru.New[string, kubernetes.Interface](size),
+	}, nil
+}
+
+// NewClusterSetForConfigAndClient creates a ClusterSet whose clients share the httpClient.
+func NewClusterSetForConfigAndClient(cfg *rest.Config, httpClient *http.Client, size int) (*ClusterSet, error) {
+	return NewClusterSetForConfig(cfg, size, WithHTTPClient(httpClient))
+}
+
+// Cluster returns the client of the resources synced from the cluster.
+func (s *ClusterSet) Cluster(cluster string) (kubernetes.Interface, error) {
+	if kubeClient, ok := s.clusters.Get(cluster); ok {
+		return kubeClient, nil
+	}
+
+	config, err := s.options.ClusterConfigFor(s.config, cluster)
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := kubernetes.NewForConfigAndClient(config, s.options.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+	s.clusters.Add(cluster, kubeClient)
+	return kubeClient, nil
+}
+
+// SharedRateLimiter returns the rate limiter of config, or creates the token bucket rate limiter of the QPS
+// and burst of config, it is set to the configs of the clients which share the rate limit, e.g. the clients of a ClusterSet.
+// It returns nil if the QPS is not positive, i.e. the requests are not rate limited, or the burst is not positive.
+func SharedRateLimiter(config *rest.Config) flowcontrol.RateLimiter {
+	if config.RateLimiter != nil || config.QPS <= 0 || config.Burst <= 0 {
+		// the invalid burst is reported by the rest client
+		return config.RateLimiter
+	}
+	return flowcontrol.NewTokenBucketRateLimiter(config.QPS, config.Burst)
+}
+
+// HTTPClient returns the http.Client shared by the clients of the ClusterSet.
+func (s *ClusterSet) HTTPClient() *http.Client {
+	return s.options.HTTPClient
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/clusterpedia-io/client-go/client"
+	"github.com/clusterpedia-io/client-go/tools/clusterpediatest"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type countingRoundTripper struct {
+	requests atomic.Int32
+}
+
+func (rt *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClusterSet(t *testing.T) {
+	server := clusterpediatest.NewTestServer(t)
+	for _, cluster := range []string{"cluster-1", "cluster-2", "cluster-3"} {
+		if err := server.AddObjects(cluster, &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx-" + cluster, Namespace: "default"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transport := &countingRoundTripper{}
+	set, err := client.NewClusterSetForConfigAndClient(server.RESTConfig(), &http.Client{Transport: transport}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deployments, err := set.AppsV1().Deployments("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deployments.Items) != 3 {
+		t.Errorf("Unexpect deployments of all clusters: %d", len(deployments.Items))
+	}
+
+	clients := make(map[string]kubernetes.Interface)
+	for _, cluster := range []string{"cluster-1", "cluster-2", "cluster-3"} {
+		kubeClient, err := set.Cluster(cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clients[cluster] = kubeClient
+		deployments, err := kubeClient.AppsV1().Deployments("default").List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deployments.Items) != 1 || deployments.Items[0].Name != "nginx-"+cluster {
+			t.Errorf("Unexpect deployments of %s: %v", cluster, deployments.Items)
+		}
+	}
+	if requests := transport.requests.Load(); requests != 4 {
+		t.Errorf("Expect all requests are sent by the shared http client, got: %d", requests)
+	}
+
+	limiter := set.CoreV1().RESTClient().GetRateLimiter()
+	for cluster, kubeClient := range clients {
+		if kubeClient.CoreV1().RESTClient().GetRateLimiter() != limiter || limiter == nil {
+			t.Errorf("Expect the client of %s shares the rate limiter of the cluster set", cluster)
+		}
+	}
+
+	if kubeClient, _ := set.Cluster("cluster-3"); kubeClient != clients["cluster-3"] {
+		t.Errorf("Expect the client of cluster-3 is cached")
+	}
+	// the cache size is 2, cluster-1 is evicted by cluster-3
+	if kubeClient, _ := set.Cluster("cluster-1"); kubeClient == clients["cluster-1"] {
+		t.Errorf("Expect the client of cluster-1 is evicted")
+	}
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/clusterpedia-io/client-go/constants"
+	"github.com/clusterpedia-io/client-go/observer"
+	"github.com/clusterpedia-io/client-go/responsecache"
+	"github.com/clusterpedia-io/client-go/retry"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+// Option configures the clients created by the constructors of the client, dynamic,
+// customclient and clusterpediaclient packages.
+//
+// The options override the fields of the rest.Config passed to the constructors,
+// the fields which are still not set use the defaults:
+//
+//	QPS:       DefaultQPS (2000)
+//	Burst:     DefaultBurst (2000)
+//	Timeout:   DefaultTimeoutSeconds (10s)
+//	UserAgent: rest.DefaultKubernetesUserAgent()
+//
+// The defaults are set for the single clusterpedia apiserver shared by the clients of a process,
+// use WithRateLimit to lower them for the batch jobs. The clients of the clusterpedia APIs created by
+// the clusterpediaclient package keep the defaults of client-go, only the options are applied.
+type Option func(*Options)
+
+// Options are the options of the clients, see Option.
+type Options struct {
+	QPS       float32
+	Burst     int
+	Timeout   time.Duration
+	UserAgent string
+
+	// Scheme is used by the clients which decode the typed objects, e.g. the controller-runtime client
+	// and customclient, the default scheme of the client is used if it is nil.
+	Scheme *runtime.Scheme
+
+	// HTTPClient is shared by the clients if it is set,
+	// otherwise each constructor creates a http.Client from the rest.Config.
+	HTTPClient *http.Client
+
+	// Cluster scopes the clients to the resources of the cluster, the resources of all of the clusters
+	// are requested if it is empty.
+	Cluster string
+
+	// BasePath is the path of the resources served by clusterpedia, constants.ClusterPediaAPIPath is used if it is empty.
+	BasePath string
+
+	// Observers observe the requests of the clients, they wrap the transport of the rest.Config,
+	// or the transport of HTTPClient if it is set.
+	Observers []observer.Observer
+
+	// WrapTransports wrap the transport like the observers, e.g. the transport of the metrics package.
+	WrapTransports []transport.WrapperFunc
+
+	// Retry retries the idempotent reads which failed transiently if it is set, every attempt is
+	// seen by the observers and the transport wrappers, see retry.Attempt.
+	Retry *retry.Policy
+
+	// ResponseCache serves the identical queries of the clients from the cache if it is set,
+	// the requests served from the cache are not seen by the observers and the transport wrappers.
+	ResponseCache *responsecache.Cache
+}
+
+// NewOptions returns the options with opts applied.
+func NewOptions(opts ...Option) *Options {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// WithRateLimit sets the QPS and burst of the requests.
+func WithRateLimit(qps float32, burst int) Option {
+	return func(o *Options) {
+		o.QPS, o.Burst = qps, burst
+	}
+}
+
+// WithTimeout sets the timeout of the requests.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = timeout
+	}
+}
+
+// WithUserAgent sets the user agent of the requests.
+func WithUserAgent(userAgent string) Option {
+	return func(o *Options) {
+		o.UserAgent = userAgent
+	}
+}
+
+// WithScheme sets the scheme used to decode the typed objects.
+func WithScheme(scheme *runtime.Scheme) Option {
+	return func(o *Options) {
+		o.Scheme = scheme
+	}
+}
+
+// WithHTTPClient sets the http.Client of the clients, e.g. to share the connections between the clients.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = httpClient
+	}
+}
+
+// WithCluster scopes the clients to the resources of the cluster.
+func WithCluster(cluster string) Option {
+	return func(o *Options) {
+		o.Cluster = cluster
+	}
+}
+
+// WithBasePath sets the path of the resources served by clusterpedia, see ConfigForBasePath.
+func WithBasePath(basePath string) Option {
+	return func(o *Options) {
+		o.BasePath = basePath
+	}
+}
+
+// WithObserver adds the observer of the requests, see the observer package for the built-in observers.
+func WithObserver(obs observer.Observer) Option {
+	return func(o *Options) {
+		o.Observers = append(o.Observers, obs)
+	}
+}
+
+// WithWrapTransport adds a wrapper of the transport of the clients.
+func WithWrapTransport(wrap transport.WrapperFunc) Option {
+	return func(o *Options) {
+		o.WrapTransports = append(o.WrapTransports, wrap)
+	}
+}
+
+// WithRetry sets the retry policy of the idempotent reads, see retry.Policy.
+func WithRetry(policy retry.Policy) Option {
+	return func(o *Options) {
+		o.Retry = &policy
+	}
+}
+
+// WithResponseCache caches the responses of the identical queries, the cache can be shared by the clients.
+//
+// The responses are scoped by the credentials of the requests and the TLS identity of the rest.Config,
+// i.e. the client certificate, the exec provider and the custom transport, or by the transport of
+// the http client option if it is set, see responsecache.Cache.
+func WithResponseCache(cache *responsecache.Cache) Option {
+	return func(o *Options) {
+		o.ResponseCache = cache
+	}
+}
+
+// ConfigFor returns the config of the clusterpedia resources, or the resources of the cluster
+// if the cluster option is set, with the options applied.
+func (o *Options) ConfigFor(cfg *rest.Config) (*rest.Config, error) {
+	if o.Cluster != "" {
+		return o.ClusterConfigFor(cfg, o.Cluster)
+	}
+
+	config, err := ConfigForBasePath(cfg, o.basePath())
+	if err != nil {
+		return nil, err
+	}
+	o.ApplyTo(config)
+	return config, nil
+}
+
+// ClusterConfigFor returns the config of the resources of the cluster with the options applied,
+// the cluster option is ignored.
+func (o *Options) ClusterConfigFor(cfg *rest.Config, cluster string) (*rest.Config, error) {
+	config, err := ClusterConfigForBasePath(cfg, o.basePath(), cluster)
+	if err != nil {
+		return nil, err
+	}
+	o.ApplyTo(config)
+	return config, nil
+}
+
+// ApplyTo overrides the fields of config by the options and sets the defaults of the fields which are not set,
+// the host of config is not changed.
+func (o *Options) ApplyTo(config *rest.Config) {
+	o.Override(config)
+	setConfigDefaults(config)
+}
+
+// Override overrides the fields of config by the options like ApplyTo, but the defaults are not set,
+// so the fields which are not set keep the defaults of client-go.
+func (o *Options) Override(config *rest.Config) {
+	if o.QPS != 0 {
+		config.QPS = o.QPS
+	}
+	if o.Burst != 0 {
+		config.Burst = o.Burst
+	}
+	if o.Timeout != 0 {
+		config.Timeout = o.Timeout
+	}
+	if o.UserAgent != "" {
+		config.UserAgent = o.UserAgent
+	}
+	if wrap := o.wrapTransport(tlsIdentity(config)); wrap != nil {
+		config.Wrap(wrap)
+	}
+}
+
+// HTTPClientFor returns the http.Client option, or creates a http.Client for the config.
+func (o *Options) HTTPClientFor(config *rest.Config) (*http.Client, error) {
+	if o.HTTPClient == nil {
+		return rest.HTTPClientFor(config)
+	}
+	rt := o.HTTPClient.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	// the credentials of the transport are unknown, the cached responses are scoped by the transport
+	wrap := o.wrapTransport(fmt.Sprintf("transport=%p", rt))
+	if wrap == nil {
+		return o.HTTPClient, nil
+	}
+
+	httpClient := *o.HTTPClient
+	httpClient.Transport = wrap(rt)
+	return &httpClient, nil
+}
+
+// wrapTransport returns the wrapper of the response cache, the retry, the observers and the transport wrappers.
+// The observers wrap the other wrappers, so they see the requests processed by the wrappers,
+// the retry wraps the observers, so that every attempt is observed,
+// and the response cache is the outermost, so that the coalesced requests are sent and retried once,
+// the responses cached by the transports of the different scopes are not shared.
+func (o *Options) wrapTransport(scope string) transport.WrapperFunc {
+	wrappers := append([]transport.WrapperFunc(nil), o.WrapTransports...)
+	if len(o.Observers) > 0 {
+		observers := o.Observers
+		wrappers = append(wrappers, func(rt http.RoundTripper) http.RoundTripper {
+			return observer.WrapTransport(rt, observers...)
+		})
+	}
+	if o.Retry != nil {
+		policy := *o.Retry
+		wrappers = append(wrappers, func(rt http.RoundTripper) http.RoundTripper {
+			return retry.WrapTransport(rt, policy)
+		})
+	}
+	if o.ResponseCache != nil {
+		wrappers = append(wrappers, o.ResponseCache.WrapTransportWithScope(scope))
+	}
+	if len(wrappers) == 0 {
+		return nil
+	}
+	return transport.Wrappers(wrappers...)
+}
+
+// tlsIdentity returns the hash of the credentials of config which are not sent in the request headers,
+// the client-go wraps the transport by the auth wrappers, so the other credentials are seen by the wrappers.
+func tlsIdentity(config *rest.Config) string {
+	identity := sha256.New()
+	fmt.Fprintf(identity, "cert=%s\nkey=%s\n", config.CertFile, config.KeyFile)
+	identity.Write(config.CertData)
+	if exec := config.ExecProvider; exec != nil {
+		fmt.Fprintf(identity, "\nexec=%s %q %v\n", exec.Command, exec.Args, exec.Env)
+	}
+	if config.Transport != nil {
+		fmt.Fprintf(identity, "transport=%p\n", config.Transport)
+	}
+	return hex.EncodeToString(identity.Sum(nil))
+}
+
+func (o *Options) basePath() string {
+	if o.BasePath == "" {
+		return constants.ClusterPediaAPIPath
+	}
+	return o.BasePath
+}
@@ -0,0 +1,479 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/clusterpedia-io/client-go/client"
+	"github.com/clusterpedia-io/client-go/clusterpediaclient"
+	"github.com/clusterpedia-io/client-go/constants"
+	"github.com/clusterpedia-io/client-go/customclient"
+	"github.com/clusterpedia-io/client-go/dynamic"
+	"github.com/clusterpedia-io/client-go/observer"
+	"github.com/clusterpedia-io/client-go/responsecache"
+	"github.com/clusterpedia-io/client-go/retry"
+	"github.com/clusterpedia-io/client-go/tools/builder"
+	"github.com/clusterpedia-io/client-go/tools/clusterpediatest"
+
+	clusterpediav1beta1 "github.com/clusterpedia-io/api/clusterpedia/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+func TestOptionsConfigFor(t *testing.T) {
+	const host = "https://10.6.0.1:6443"
+	testCase := []struct {
+		name   string
+		config rest.Config
+		opts   []client.Option
+		expect rest.Config
+	}{
+		{
+			"defaults",
+			rest.Config{Host: host},
+			nil,
+			rest.Config{
+				Host: host + "/apis/clusterpedia.io/v1beta1/resources", QPS: client.DefaultQPS, Burst: client.DefaultBurst,
+				Timeout: client.DefaultTimeoutSeconds * time.Second, UserAgent: rest.DefaultKubernetesUserAgent(),
+			},
+		},
+		{
+			"config",
+			rest.Config{Host: host, QPS: 50, Burst: 100, Timeout: time.Minute, UserAgent: "batch"},
+			nil,
+			rest.Config{Host: host + "/apis/clusterpedia.io/v1beta1/resources", QPS: 50, Burst: 100, Timeout: time.Minute, UserAgent: "batch"},
+		},
+		{
+			"options override config",
+			rest.Config{Host: host, QPS: 50, Burst: 100, Timeout: time.Minute, UserAgent: "batch"},
+			[]client.Option{client.WithRateLimit(5, 10), client.WithTimeout(30 * time.Second), client.WithUserAgent("report")},
+			rest.Config{Host: host + "/apis/clusterpedia.io/v1beta1/resources", QPS: 5, Burst: 10, Timeout: 30 * time.Second, UserAgent: "report"},
+		},
+		{
+			"cluster and base path",
+			rest.Config{Host: host + "/prefix", QPS: 5, Burst: 10, Timeout: time.Second, UserAgent: "batch"},
+			[]client.Option{client.WithCluster("cluster-1"), client.WithBasePath("/resources")},
+			rest.Config{Host: host + "/prefix/resources/clusters/cluster-1", QPS: 5, Burst: 10, Timeout: time.Second, UserAgent: "batch"},
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := client.NewOptions(test.opts...).ConfigFor(&test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Host != test.expect.Host || config.QPS != test.expect.QPS || config.Burst != test.expect.Burst ||
+				config.Timeout != test.expect.Timeout || config.UserAgent != test.expect.UserAgent {
+				t.Errorf("Unexpect config: %+v, expect: %+v", config, test.expect)
+			}
+		})
+	}
+}
+
+func TestClusterpediaClientDefaults(t *testing.T) {
+	testCase := []struct {
+		name          string
+		opts          []client.Option
+		expectTimeout string
+	}{
+		{"defaults of client-go", nil, ""},
+		{"timeout option", []client.Option{client.WithTimeout(time.Minute)}, "1m0s"},
+	}
+
+	fake := clusterpediatest.NewServer()
+	fake.AddCollectionResource(clusterpediav1beta1.CollectionResource{
+		ObjectMeta:    metav1.ObjectMeta{Name: "workloads"},
+		ResourceTypes: []clusterpediav1beta1.CollectionResourceType{{Group: "apps", Resource: "deployments"}},
+	})
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			var timeout string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				timeout = r.URL.Query().Get("timeout")
+				fake.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			cc, err := clusterpediaclient.NewForConfig(&rest.Config{Host: server.URL}, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cc.PediaClusterV1beta1().CollectionResource().Get(context.TODO(), "workloads", metav1.GetOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if timeout != test.expectTimeout {
+				t.Errorf("Unexpect timeout: %q, expect: %q", timeout, test.expectTimeout)
+			}
+		})
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	server := clusterpediatest.NewTestServer(t)
+	if err := server.AddObjects("cluster-1", &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	transport := &countingRoundTripper{}
+	httpClient := client.WithHTTPClient(&http.Client{Transport: transport})
+
+	kubeClient, err := client.NewClusterForConfig(server.RESTConfig(), "cluster-1", httpClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kubeClient.AppsV1().Deployments("default").Get(context.TODO(), "nginx", metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := client.New(server.RESTConfig(), httpClient, client.WithCluster("cluster-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(context.TODO(), deployments); err != nil {
+		t.Fatal(err)
+	}
+	if len(deployments.Items) != 1 {
+		t.Errorf("Unexpect deployments: %d", len(deployments.Items))
+	}
+
+	// the controller-runtime client also requests the discovery by the http client
+	if requests := transport.requests.Load(); requests < 3 {
+		t.Errorf("Expect all requests are sent by the http client option, got: %d", requests)
+	}
+}
+
+func TestWithObserver(t *testing.T) {
+	server := clusterpediatest.NewTestServer(t)
+	if err := server.AddObjects("cluster-1", &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	listOptions := builder.ListOptionsBuilder().Clusters("cluster-1").Options()
+
+	recorder := &observer.Recorder{}
+	testCase := []struct {
+		name    string
+		request func(opts ...client.Option) error
+	}{
+		{"kubernetes", func(opts ...client.Option) error {
+			kubeClient, err := client.NewForConfig(server.RESTConfig(), opts...)
+			if err != nil {
+				return err
+			}
+			_, err = kubeClient.AppsV1().Deployments("").List(context.TODO(), listOptions)
+			return err
+		}},
+		{"dynamic", func(opts ...client.Option) error {
+			dc, err := dynamic.NewForConfig(server.RESTConfig(), opts...)
+			if err != nil {
+				return err
+			}
+			_, err = dc.Resource(deployments).List(context.TODO(), listOptions)
+			return err
+		}},
+		{"customclient", func(opts ...client.Option) error {
+			c, err := customclient.NewForConfig(server.RESTConfig(), opts...)
+			if err != nil {
+				return err
+			}
+			return c.Resource(deployments).List(context.TODO(), listOptions, nil, &appsv1.DeploymentList{})
+		}},
+		{"clusterpediaclient", func(opts ...client.Option) error {
+			cc, err := clusterpediaclient.NewForConfig(server.RESTConfig(), opts...)
+			if err != nil {
+				return err
+			}
+			_, err = cc.PediaClusterV1beta1().CollectionResource().List(context.TODO(), listOptions)
+			return err
+		}},
+		{"http client", func(opts ...client.Option) error {
+			opts = append(opts, client.WithHTTPClient(&http.Client{}))
+			kubeClient, err := client.NewForConfig(server.RESTConfig(), opts...)
+			if err != nil {
+				return err
+			}
+			_, err = kubeClient.AppsV1().Deployments("").List(context.TODO(), listOptions)
+			return err
+		}},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			recorder.Reset()
+			if err := test.request(client.WithObserver(recorder)); err != nil {
+				t.Fatal(err)
+			}
+
+			events := recorder.Events()
+			if len(events) != 1 {
+				t.Fatalf("Unexpect events: %+v", events)
+			}
+			if events[0].StatusCode != http.StatusOK || events[0].ResponseSize == 0 {
+				t.Errorf("Unexpect event: %+v", events[0])
+			}
+			if clusters := events[0].SearchLabels[constants.SearchLabelClusters]; !reflect.DeepEqual(clusters, []string{"cluster-1"}) {
+				t.Errorf("Unexpect search labels: %v", events[0].SearchLabels)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	fake := clusterpediatest.NewServer()
+	fake.AddCollectionResource(clusterpediav1beta1.CollectionResource{
+		ObjectMeta: metav1.ObjectMeta{Name: "workloads"},
+		ResourceTypes: []clusterpediav1beta1.CollectionResourceType{
+			{Group: "apps", Resource: "deployments"},
+		},
+	})
+	if err := fake.AddObjects("cluster-1", &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// every request is rejected once while the storage is under load
+	var lock sync.Mutex
+	rejected := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		reject := !rejected[r.URL.String()]
+		rejected[r.URL.String()] = true
+		lock.Unlock()
+		if reject {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	config := &rest.Config{Host: server.URL}
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+	recorder := &observer.Recorder{}
+	opts := []client.Option{client.WithRetry(retry.Policy{InitialBackoff: time.Millisecond}), client.WithObserver(recorder)}
+	testCase := []struct {
+		name    string
+		request func() error
+	}{
+		{"customclient", func() error {
+			c, err := customclient.NewForConfig(config, opts...)
+			if err != nil {
+				return err
+			}
+			return c.Resource(deployments).List(context.TODO(), metav1.ListOptions{}, nil, &appsv1.DeploymentList{})
+		}},
+		{"collection resource", func() error {
+			cc, err := clusterpediaclient.NewForConfig(config, opts...)
+			if err != nil {
+				return err
+			}
+			_, err = cc.PediaClusterV1beta1().CollectionResource().Fetch(context.TODO(), "workloads", metav1.ListOptions{}, nil)
+			return err
+		}},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			recorder.Reset()
+			if err := test.request(); err != nil {
+				t.Fatal(err)
+			}
+
+			events := recorder.Events()
+			if len(events) != 2 {
+				t.Fatalf("Unexpect events: %+v", events)
+			}
+			if events[0].StatusCode != http.StatusTooManyRequests || events[0].Attempt != 0 ||
+				events[1].StatusCode != http.StatusOK || events[1].Attempt != 1 {
+				t.Errorf("Expect the retry is observed, events: %+v", events)
+			}
+		})
+	}
+}
+
+func TestWithResponseCache(t *testing.T) {
+	fake := clusterpediatest.NewServer()
+	fake.AddCollectionResource(clusterpediav1beta1.CollectionResource{
+		ObjectMeta: metav1.ObjectMeta{Name: "workloads"},
+		ResourceTypes: []clusterpediav1beta1.CollectionResourceType{
+			{Group: "apps", Resource: "deployments"},
+		},
+	})
+	if err := fake.AddObjects("cluster-1", &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fake.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	config := &rest.Config{Host: server.URL}
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+	cache := responsecache.New(10, time.Minute)
+	c, err := customclient.NewForConfig(config, client.WithResponseCache(cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc, err := clusterpediaclient.NewForConfig(config, client.WithResponseCache(cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []metav1.ListOptions{
+		builder.ListOptionsBuilder().Clusters("cluster-1").Namespaces("default").Options(),
+		builder.ListOptionsBuilder().Namespaces("default").Clusters("cluster-1").Options(),
+	} {
+		list := &appsv1.DeploymentList{}
+		if err := c.Resource(deployments).List(context.TODO(), opts, nil, list); err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != 1 {
+			t.Errorf("Unexpect deployments: %d", len(list.Items))
+		}
+
+		collection, err := cc.PediaClusterV1beta1().CollectionResource().Fetch(context.TODO(), "workloads", opts, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(collection.Items) != 1 {
+			t.Errorf("Unexpect collection items: %d", len(collection.Items))
+		}
+	}
+
+	if requests.Load() != 2 {
+		t.Errorf("Expect the identical queries are served from the cache, requests: %d", requests.Load())
+	}
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 2 || stats.Entries != 2 {
+		t.Errorf("Unexpect stats: %+v", stats)
+	}
+}
+
+// clientCert returns a self-signed client certificate of the common name in PEM.
+func clientCert(t *testing.T, commonName string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestWithResponseCacheClientCerts(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if len(r.TLS.PeerCertificates) == 0 {
+			http.Error(w, "no client certificate", http.StatusUnauthorized)
+			return
+		}
+		_, _ = io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	cache := responsecache.New(10, time.Minute)
+	certs := map[string][2][]byte{}
+	for _, user := range []string{"user-1", "user-2"} {
+		certData, keyData := clientCert(t, user)
+		certs[user] = [2][]byte{certData, keyData}
+	}
+	get := func(user string) string {
+		certData, keyData := certs[user][0], certs[user][1]
+		config, err := client.NewOptions(client.WithResponseCache(cache)).ConfigFor(&rest.Config{
+			Host:            server.URL,
+			TLSClientConfig: rest.TLSClientConfig{Insecure: true, CertData: certData, KeyData: keyData},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		httpClient, err := rest.HTTPClientFor(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := httpClient.Get(server.URL + config.APIPath + "/api/v1/pods")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	for _, user := range []string{"user-1", "user-2", "user-1"} {
+		if body := get(user); body != user {
+			t.Errorf("Unexpect response of %s: %s", user, body)
+		}
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expect the responses are only shared by the same client certificate, requests: %d", requests.Load())
+	}
+}
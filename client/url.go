@@ -0,0 +1,89 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/clusterpedia-io/client-go/constants"
+
+	"k8s.io/apimachinery/pkg/api/validation/path"
+)
+
+// resourcesHost returns the host of the clusterpedia resources, basePath is the path of the resources
+// under the host, e.g. constants.ClusterPediaAPIPath, and the path of the host is kept as the prefix.
+//
+// The resources path or the path of a cluster composed in the host is detected, so composing again
+// doesn't change the host, and the path of the cluster is replaced by the resources path.
+func resourcesHost(host, basePath string) (string, error) {
+	return composeHost(host, func(p string) string {
+		return resourcesPath(p, basePath)
+	})
+}
+
+// clusterResourcesHost returns the host of the clusterpedia resources of the cluster like resourcesHost,
+// the cluster name is escaped in the path.
+func clusterResourcesHost(host, basePath, cluster string) (string, error) {
+	if errs := path.IsValidPathSegmentName(cluster); cluster == "" || len(errs) != 0 {
+		return "", fmt.Errorf("invalid cluster name %q: %s", cluster, strings.Join(errs, ", "))
+	}
+	return composeHost(host, func(p string) string {
+		return resourcesPath(p, basePath) + constants.ClusterAPIPath + cluster
+	})
+}
+
+// composeHost replaces the path of the host by compose, the host may be a URL or host:port without the scheme.
+func composeHost(host string, compose func(p string) string) (string, error) {
+	hasScheme := strings.Contains(host, "://")
+	raw := host
+	if !hasScheme {
+		raw = "//" + host
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid host %q: %w", host, err)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid host %q: the host is empty", host)
+	}
+
+	u.Path, u.RawPath = compose(u.Path), ""
+	if hasScheme {
+		return u.String(), nil
+	}
+	return strings.TrimPrefix(u.String(), "//"), nil
+}
+
+// resourcesPath returns the resources path under the path p, the trailing slash of p is ignored.
+func resourcesPath(p, basePath string) string {
+	p, basePath = strings.TrimSuffix(p, "/"), strings.TrimSuffix(basePath, "/")
+	if basePath != "" && !strings.HasPrefix(basePath, "/") {
+		basePath = "/" + basePath
+	}
+
+	// strip the path of a cluster
+	if i := strings.LastIndex(p, constants.ClusterAPIPath); i != -1 &&
+		!strings.Contains(p[i+len(constants.ClusterAPIPath):], "/") && strings.HasSuffix(p[:i], basePath) {
+		p = p[:i]
+	}
+	if !strings.HasSuffix(p, basePath) {
+		p += basePath
+	}
+	return p
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+const resources = "/apis/clusterpedia.io/v1beta1/resources"
+
+func TestConfigFor(t *testing.T) {
+	testCase := []struct {
+		name      string
+		host      string
+		basePath  string
+		expect    string
+		expectErr bool
+	}{
+		{"host", "https://10.6.0.1:6443", resources, "https://10.6.0.1:6443" + resources, false},
+		{"trailing slash", "https://10.6.0.1:6443/", resources, "https://10.6.0.1:6443" + resources, false},
+		{"without scheme", "10.6.0.1:6443", resources, "10.6.0.1:6443" + resources, false},
+		{"ipv6", "https://[::1]:6443", resources, "https://[::1]:6443" + resources, false},
+		{"path prefix", "https://gateway.example.com/clusterpedia", resources, "https://gateway.example.com/clusterpedia" + resources, false},
+		{"path prefix with trailing slash", "https://gateway.example.com/clusterpedia/", resources, "https://gateway.example.com/clusterpedia" + resources, false},
+		{"composed", "https://10.6.0.1:6443" + resources, resources, "https://10.6.0.1:6443" + resources, false},
+		{"composed with trailing slash", "https://10.6.0.1:6443" + resources + "/", resources, "https://10.6.0.1:6443" + resources, false},
+		{"composed cluster", "https://10.6.0.1:6443" + resources + "/clusters/cluster-1", resources, "https://10.6.0.1:6443" + resources, false},
+		{"prefix like a cluster path", "https://gateway.example.com/clusters/clusterpedia", resources, "https://gateway.example.com/clusters/clusterpedia" + resources, false},
+		{"base path without slash", "https://10.6.0.1:6443", "resources", "https://10.6.0.1:6443/resources", false},
+		{"standalone base path", "https://clusterpedia.example.com", "/", "https://clusterpedia.example.com", false},
+		{"empty base path", "https://clusterpedia.example.com/prefix/", "", "https://clusterpedia.example.com/prefix", false},
+		{"empty base path with cluster", "https://clusterpedia.example.com/clusters/cluster-1", "", "https://clusterpedia.example.com", false},
+		{"empty host", "", resources, "", true},
+		{"invalid host", "https://10.6.0.1:port", resources, "", true},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := ConfigForBasePath(&rest.Config{Host: test.host}, test.basePath)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("Unexpect error: %v, expect error: %v", err, test.expectErr)
+			}
+			if err != nil {
+				return
+			}
+			if config.Host != test.expect {
+				t.Errorf("Unexpect host: %s, expect: %s", config.Host, test.expect)
+			}
+
+			again, err := ConfigForBasePath(config, test.basePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.Host != config.Host {
+				t.Errorf("Expect composing again is idempotent, got: %s", again.Host)
+			}
+		})
+	}
+}
+
+func TestClusterConfigFor(t *testing.T) {
+	testCase := []struct {
+		name      string
+		host      string
+		cluster   string
+		expect    string
+		expectErr bool
+	}{
+		{"host", "https://10.6.0.1:6443", "cluster-1", "https://10.6.0.1:6443" + resources + "/clusters/cluster-1", false},
+		{"path prefix", "https://gateway.example.com/clusterpedia/", "cluster-1", "https://gateway.example.com/clusterpedia" + resources + "/clusters/cluster-1", false},
+		{"composed", "https://10.6.0.1:6443" + resources, "cluster-1", "https://10.6.0.1:6443" + resources + "/clusters/cluster-1", false},
+		{"composed cluster", "https://10.6.0.1:6443" + resources + "/clusters/cluster-1", "cluster-1", "https://10.6.0.1:6443" + resources + "/clusters/cluster-1", false},
+		{"another cluster", "https://10.6.0.1:6443" + resources + "/clusters/cluster-1/", "cluster-2", "https://10.6.0.1:6443" + resources + "/clusters/cluster-2", false},
+		{"escaped", "https://10.6.0.1:6443", "cluster 1?#", "https://10.6.0.1:6443" + resources + "/clusters/cluster%201%3F%23", false},
+		{"empty cluster", "https://10.6.0.1:6443", "", "", true},
+		{"dot dot", "https://10.6.0.1:6443", "..", "", true},
+		{"slash", "https://10.6.0.1:6443", "cluster-1/../..", "", true},
+		{"percent", "https://10.6.0.1:6443", "cluster%2F1", "", true},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := ClusterConfigFor(&rest.Config{Host: test.host}, test.cluster)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("Unexpect error: %v, expect error: %v", err, test.expectErr)
+			}
+			if err != nil {
+				return
+			}
+			if config.Host != test.expect {
+				t.Errorf("Unexpect host: %s, expect: %s", config.Host, test.expect)
+			}
+
+			again, err := ClusterConfigFor(config, test.cluster)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.Host != config.Host {
+				t.Errorf("Expect composing again is idempotent, got: %s", again.Host)
+			}
+		})
+	}
+}
+
+func TestClusterRequestURL(t *testing.T) {
+	kubeClient, err := NewClusterForConfig(&rest.Config{Host: "https://gateway.example.com/clusterpedia/"}, "cluster 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := kubeClient.AppsV1().RESTClient().Get().Namespace("default").Resource("deployments").URL().String()
+	expect := "https://gateway.example.com/clusterpedia" + resources + "/clusters/cluster%201/apis/apps/v1/namespaces/default/deployments?timeout=10s"
+	if url != expect {
+		t.Errorf("Unexpect request url: %s, expect: %s", url, expect)
+	}
+}
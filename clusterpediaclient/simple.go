@@ -17,12 +17,10 @@ limitations under the License.
 package clusterpediaclient
 
 import (
-	"fmt"
-
+	"github.com/clusterpedia-io/client-go/client"
 	"github.com/clusterpedia-io/client-go/clusterpediaclient/v1beta1"
 
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/util/flowcontrol"
 )
 
 type ClusterpediaClient struct {
@@ -34,18 +32,21 @@ func (c *ClusterpediaClient) PediaClusterV1beta1() v1beta1.ClusterPediaV1beta1 {
 	return c.pediaClusterClient
 }
 
-func NewForConfig(cfg *rest.Config) (*ClusterpediaClient, error) {
-	configShallowCopy := cfg
-	if configShallowCopy.RateLimiter == nil && configShallowCopy.QPS > 0 {
-		if configShallowCopy.Burst <= 0 {
-			return nil, fmt.Errorf("burst is required to be greater than 0 when RateLimiter is not set and QPS is set to greater than 0")
-		}
-		configShallowCopy.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(configShallowCopy.QPS, configShallowCopy.Burst)
+// NewForConfig returns the client of the clusterpedia APIs, e.g. the collection resources,
+// see client.Option for the options, the cluster and base path options are not used.
+// The QPS, burst and timeout which are not set by the options or cfg keep the defaults of client-go.
+func NewForConfig(cfg *rest.Config, opts ...client.Option) (*ClusterpediaClient, error) {
+	options := client.NewOptions(opts...)
+	config := rest.CopyConfig(cfg)
+	options.Override(config)
+
+	httpClient, err := options.HTTPClientFor(config)
+	if err != nil {
+		return nil, err
 	}
 
-	var err error
 	var cc ClusterpediaClient
-	cc.pediaClusterClient, err = v1beta1.NewForConfig(configShallowCopy)
+	cc.pediaClusterClient, err = v1beta1.NewForConfigAndClient(config, httpClient)
 	if err != nil {
 		return nil, err
 	}
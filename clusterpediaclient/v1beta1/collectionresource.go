@@ -25,6 +25,8 @@ import (
 
 	clusterpediav1beta1 "github.com/clusterpedia-io/api/clusterpedia/v1beta1"
 	scheme "github.com/clusterpedia-io/client-go/clusterpediaclient/scheme"
+	"github.com/clusterpedia-io/client-go/observer"
+	"github.com/clusterpedia-io/client-go/tools/builder"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
@@ -32,6 +34,8 @@ import (
 
 type ClusterPediaV1beta1 interface {
 	CollectionResource() CollectionResourceInterface
+	// Debug returns a copy of the client whose requests are marked as observer.Event.Debug for the observers,
+	// or logged by the default logger of slog if there is no observer.
 	Debug() ClusterPediaV1beta1
 }
 
@@ -82,15 +86,23 @@ func (c *ClusterPediaV1beta1Client) CollectionResource() CollectionResourceInter
 	return &CollectionResource{client: c.restClient, openDebug: c.openDebug}
 }
 
+// Debug returns a copy of the client whose requests are marked as observer.Event.Debug for the observers
+// registered by client.WithObserver, the URLs of the requests are logged by the default logger of slog
+// if there is no observer. c is not changed, so it is safe to call Debug on a client shared by goroutines.
 func (c *ClusterPediaV1beta1Client) Debug() ClusterPediaV1beta1 {
-	c.openDebug = true
-	return c
+	ret := *c
+	ret.openDebug = true
+	return &ret
 }
 
 type CollectionResourceInterface interface {
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*clusterpediav1beta1.CollectionResource, error)
 	List(ctx context.Context, opts metav1.ListOptions) (*clusterpediav1beta1.CollectionResourceList, error)
+	// Fetch returns the items of the collection resource, params can be built by builder.ParamsBuilder().
 	Fetch(ctx context.Context, name string, opts metav1.ListOptions, params map[string]string) (*clusterpediav1beta1.CollectionResource, error)
+	// FetchWithParams is like Fetch, params may be the builder.ParamsBuilder() or builder.Params,
+	// the invalid params are returned as the error without sending the request.
+	FetchWithParams(ctx context.Context, name string, opts metav1.ListOptions, params builder.ParamsSource) (*clusterpediav1beta1.CollectionResource, error)
 }
 
 type CollectionResource struct {
@@ -105,12 +117,12 @@ func (c *CollectionResource) Get(ctx context.Context, name string, opts metav1.G
 		Name(name).
 		VersionedParams(&opts, scheme.ParameterCodec)
 
-	if c.openDebug {
-		unescape, _ := url.QueryUnescape(request.URL().String())
-		slog.Debug("CollectionResource.Get", slog.String("req.URL", unescape))
-	}
-
+	ctx = c.context(ctx)
 	err = request.Do(ctx).Into(result)
+	c.debug(ctx, "CollectionResource.Get", request)
+	if err != nil {
+		err = newCollectionResourceError(name, request.URL().String(), err)
+	}
 	return
 }
 
@@ -125,31 +137,58 @@ func (c *CollectionResource) List(ctx context.Context, opts metav1.ListOptions)
 		VersionedParams(&opts, scheme.ParameterCodec).
 		Timeout(timeout)
 
-	if c.openDebug {
-		unescape, _ := url.QueryUnescape(req.URL().String())
-		slog.Debug("CollectionResource.List", slog.String("req.URL", unescape))
-	}
-
+	ctx = c.context(ctx)
 	err = req.Do(ctx).Into(result)
+	c.debug(ctx, "CollectionResource.List", req)
+	if err != nil {
+		err = newCollectionResourceError("", req.URL().String(), err)
+	}
 	return
 }
 
-func (c *CollectionResource) Fetch(ctx context.Context, name string, opts metav1.ListOptions, params map[string]string) (result *clusterpediav1beta1.CollectionResource, err error) {
+func (c *CollectionResource) Fetch(ctx context.Context, name string, opts metav1.ListOptions, params map[string]string) (*clusterpediav1beta1.CollectionResource, error) {
+	return c.FetchWithParams(ctx, name, opts, builder.Params(params))
+}
+
+func (c *CollectionResource) FetchWithParams(ctx context.Context, name string, opts metav1.ListOptions, params builder.ParamsSource) (result *clusterpediav1beta1.CollectionResource, err error) {
 	request := c.client.Get().
 		Resource("collectionresources").
 		Name(name).
 		VersionedParams(&opts, scheme.ParameterCodec)
 
-	for p, v := range params {
-		request.Param(p, v)
+	if params != nil {
+		values, err := params.ParamsE()
+		if err != nil {
+			return nil, err
+		}
+		for p, v := range values {
+			request.Param(p, v)
+		}
 	}
 
+	result = &clusterpediav1beta1.CollectionResource{}
+	ctx = c.context(ctx)
+	err = request.Do(ctx).Into(result)
+	c.debug(ctx, "CollectionResource.Fetch", request)
+	if err != nil {
+		err = newCollectionResourceError(name, request.URL().String(), err)
+	}
+	return
+}
+
+// context marks the requests of the Debug client for the observers.
+func (c *CollectionResource) context(ctx context.Context) context.Context {
 	if c.openDebug {
-		unescape, _ := url.QueryUnescape(request.URL().String())
-		slog.Debug("CollectionResource.Fetch", slog.String("req.URL", unescape))
+		return observer.WithDebug(ctx)
 	}
+	return ctx
+}
 
-	result = &clusterpediav1beta1.CollectionResource{}
-	request.Do(ctx).Into(result)
-	return
+// debug logs the URL of the request of the Debug client by the default logger of slog
+// if the request isn't seen by any observer.
+func (c *CollectionResource) debug(ctx context.Context, msg string, req *rest.Request) {
+	if c.openDebug && !observer.Observed(ctx) {
+		unescape, _ := url.QueryUnescape(req.URL().String())
+		slog.DebugContext(ctx, msg, slog.String("req.URL", unescape))
+	}
 }
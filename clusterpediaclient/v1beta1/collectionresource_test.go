@@ -0,0 +1,113 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/clusterpedia-io/client-go/observer"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+func TestDebugConcurrently(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"apiVersion":"clusterpedia.io/v1beta1","kind":"CollectionResourceList","metadata":{},"items":[]}`)
+	}))
+	defer server.Close()
+
+	c, err := NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Debug().CollectionResource().List(context.TODO(), metav1.ListOptions{}); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := c.CollectionResource().List(context.TODO(), metav1.ListOptions{}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c.openDebug || c.CollectionResource().(*CollectionResource).openDebug {
+		t.Errorf("Expect Debug doesn't change the shared client")
+	}
+	if !c.Debug().CollectionResource().(*CollectionResource).openDebug {
+		t.Errorf("Expect the client returned by Debug marks the requests")
+	}
+}
+
+func TestDebugLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"apiVersion":"clusterpedia.io/v1beta1","kind":"CollectionResourceList","metadata":{},"items":[]}`)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	recorder := &observer.Recorder{}
+	wrap := func(rt http.RoundTripper) http.RoundTripper { return observer.WrapTransport(rt, recorder) }
+	testCase := []struct {
+		name      string
+		config    *rest.Config
+		expectLog bool
+	}{
+		{"without observer", &rest.Config{Host: server.URL}, true},
+		{"with observer", &rest.Config{Host: server.URL, WrapTransport: wrap}, false},
+	}
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			buf.Reset()
+			c, err := NewForConfig(test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Debug().CollectionResource().List(context.TODO(), metav1.ListOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if logged := strings.Contains(buf.String(), "CollectionResource.List"); logged != test.expectLog {
+				t.Errorf("Unexpect log: %q", buf.String())
+			}
+		})
+	}
+	if events := recorder.Events(); len(events) != 1 || !events[0].Debug {
+		t.Errorf("Unexpect events: %v", events)
+	}
+}
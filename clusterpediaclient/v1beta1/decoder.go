@@ -0,0 +1,144 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+
+	clusterpediav1beta1 "github.com/clusterpedia-io/api/clusterpedia/v1beta1"
+	"github.com/clusterpedia-io/client-go/constants"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+// DecodedItem is an item of a collection resource decoded into a go object.
+type DecodedItem struct {
+	// Cluster is the cluster the object comes from.
+	Cluster string
+	// Object is a typed object if its kind is known by the decoder's scheme,
+	// otherwise it is an *unstructured.Unstructured.
+	Object runtime.Object
+}
+
+// DecodedCollectionResource is a collection resource with decoded items.
+type DecodedCollectionResource struct {
+	Name          string
+	ResourceTypes []clusterpediav1beta1.CollectionResourceType
+
+	// Items are grouped by the GVK of the resource type they belong to,
+	// items that don't match any resource type are grouped by their own GVK.
+	Items map[schema.GroupVersionKind][]DecodedItem
+
+	Continue           string
+	RemainingItemCount *int64
+}
+
+// Decoder decodes the items of a collection resource.
+type Decoder struct {
+	scheme  *runtime.Scheme
+	decoder runtime.Decoder
+}
+
+// NewDecoder returns a Decoder which decodes the kinds registered in scheme into typed objects,
+// a nil scheme uses the client-go kubernetes scheme.
+func NewDecoder(scheme *runtime.Scheme) *Decoder {
+	if scheme == nil {
+		scheme = clientgoscheme.Scheme
+	}
+	return &Decoder{
+		scheme:  scheme,
+		decoder: serializer.NewCodecFactory(scheme).UniversalDeserializer(),
+	}
+}
+
+// Decode decodes all of the items of resource.
+func (d *Decoder) Decode(resource *clusterpediav1beta1.CollectionResource) (*DecodedCollectionResource, error) {
+	decoded := &DecodedCollectionResource{
+		Name:               resource.Name,
+		ResourceTypes:      resource.ResourceTypes,
+		Items:              make(map[schema.GroupVersionKind][]DecodedItem),
+		Continue:           resource.Continue,
+		RemainingItemCount: resource.RemainingItemCount,
+	}
+
+	for i, raw := range resource.Items {
+		gvk, err := json.DefaultMetaFactory.Interpret(raw.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to interpret item %d of collection resource %s: %w", i, resource.Name, err)
+		}
+		if gvk.Empty() {
+			gvk = defaultKind(resource.ResourceTypes)
+		}
+
+		item, err := d.DecodeItem(raw.Raw, gvk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode item %d of collection resource %s: %w", i, resource.Name, err)
+		}
+
+		key := resourceTypeKind(resource.ResourceTypes, *gvk)
+		decoded.Items[key] = append(decoded.Items[key], item)
+	}
+	return decoded, nil
+}
+
+// DecodeItem decodes a single raw object, gvk is used as the default kind of the object.
+func (d *Decoder) DecodeItem(raw []byte, gvk *schema.GroupVersionKind) (DecodedItem, error) {
+	obj, _, err := d.decoder.Decode(raw, gvk, nil)
+	if runtime.IsNotRegisteredError(err) {
+		obj, _, err = unstructured.UnstructuredJSONScheme.Decode(raw, gvk, nil)
+	}
+	if err != nil {
+		return DecodedItem{}, err
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return DecodedItem{}, err
+	}
+	return DecodedItem{
+		Cluster: accessor.GetAnnotations()[constants.ShadowAnnotationClusterName],
+		Object:  obj,
+	}, nil
+}
+
+// defaultKind returns the kind of the only resource type, it is used for items without type meta.
+func defaultKind(types []clusterpediav1beta1.CollectionResourceType) *schema.GroupVersionKind {
+	if len(types) != 1 || types[0].Kind == "" {
+		return nil
+	}
+	return &schema.GroupVersionKind{Group: types[0].Group, Version: types[0].Version, Kind: types[0].Kind}
+}
+
+// resourceTypeKind returns the GVK of the resource type matched by gvk,
+// the version of the resource type is optional.
+func resourceTypeKind(types []clusterpediav1beta1.CollectionResourceType, gvk schema.GroupVersionKind) schema.GroupVersionKind {
+	for _, rt := range types {
+		if rt.Group != gvk.Group || rt.Kind != gvk.Kind {
+			continue
+		}
+		if rt.Version == "" || rt.Version == gvk.Version {
+			return schema.GroupVersionKind{Group: rt.Group, Version: rt.Version, Kind: rt.Kind}
+		}
+	}
+	return gvk
+}
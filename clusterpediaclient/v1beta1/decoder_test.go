@@ -0,0 +1,71 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	clusterpediav1beta1 "github.com/clusterpedia-io/api/clusterpedia/v1beta1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestDecode(t *testing.T) {
+	resource := &clusterpediav1beta1.CollectionResource{
+		ResourceTypes: []clusterpediav1beta1.CollectionResourceType{
+			{Group: "apps", Version: "v1", Kind: "Deployment", Resource: "deployments"},
+			{Group: "apps", Kind: "DaemonSet", Resource: "daemonsets"},
+		},
+		Items: []runtime.RawExtension{
+			{Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"nginx","namespace":"default","annotations":{"shadow.clusterpedia.io/cluster-name":"cluster-1"}}}`)},
+			{Raw: []byte(`{"apiVersion":"apps/v1","kind":"DaemonSet","metadata":{"name":"agent","annotations":{"shadow.clusterpedia.io/cluster-name":"cluster-2"}}}`)},
+			{Raw: []byte(`{"apiVersion":"example.io/v1","kind":"Foo","metadata":{"name":"foo"}}`)},
+		},
+	}
+
+	decoded, err := NewDecoder(nil).Decode(resource)
+	if err != nil {
+		t.Fatalf("Unexpect error: %v", err)
+	}
+
+	deploys := decoded.Items[schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}]
+	if len(deploys) != 1 {
+		t.Fatalf("Unexpect deployments: %v", deploys)
+	}
+	if deploy, ok := deploys[0].Object.(*appsv1.Deployment); !ok || deploy.Name != "nginx" || deploys[0].Cluster != "cluster-1" {
+		t.Errorf("Unexpect deployment: %#v, cluster: %s", deploys[0].Object, deploys[0].Cluster)
+	}
+
+	daemonsets := decoded.Items[schema.GroupVersionKind{Group: "apps", Kind: "DaemonSet"}]
+	if len(daemonsets) != 1 {
+		t.Fatalf("Unexpect daemonsets: %v", daemonsets)
+	}
+	if ds, ok := daemonsets[0].Object.(*appsv1.DaemonSet); !ok || ds.Name != "agent" || daemonsets[0].Cluster != "cluster-2" {
+		t.Errorf("Unexpect daemonset: %#v, cluster: %s", daemonsets[0].Object, daemonsets[0].Cluster)
+	}
+
+	foos := decoded.Items[schema.GroupVersionKind{Group: "example.io", Version: "v1", Kind: "Foo"}]
+	if len(foos) != 1 {
+		t.Fatalf("Unexpect foos: %v", foos)
+	}
+	if foo, ok := foos[0].Object.(*unstructured.Unstructured); !ok || foo.GetName() != "foo" {
+		t.Errorf("Unexpect foo: %#v", foos[0].Object)
+	}
+}
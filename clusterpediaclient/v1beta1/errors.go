@@ -0,0 +1,94 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// searchLabelPrefixes are the prefixes of the labels interpreted by clusterpedia.
+var searchLabelPrefixes = []string{"search.clusterpedia.io/", "internalstorage.clusterpedia.io/"}
+
+// CollectionResourceError is returned when a request of collection resources fails.
+type CollectionResourceError struct {
+	// Collection is the name of the collection resource, it is empty when listing collection resources.
+	Collection string
+	// URL is the request URL.
+	URL string
+	// ErrStatus is the status returned by the server, if the request failed without
+	// a status, e.g. a connection or decoding error, only Status and Message are set.
+	ErrStatus metav1.Status
+
+	err error
+}
+
+func newCollectionResourceError(collection, url string, err error) *CollectionResourceError {
+	e := &CollectionResourceError{Collection: collection, URL: url, err: err}
+
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		e.ErrStatus = status.Status()
+	} else {
+		e.ErrStatus = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+	}
+	return e
+}
+
+func (e *CollectionResourceError) Error() string {
+	if e.Collection == "" {
+		return fmt.Sprintf("request collection resources %s: %v", e.URL, e.err)
+	}
+	return fmt.Sprintf("request collection resource %s %s: %v", e.Collection, e.URL, e.err)
+}
+
+// Unwrap returns the underlying error, so that the helpers of
+// k8s.io/apimachinery/pkg/api/errors can be used with CollectionResourceError.
+func (e *CollectionResourceError) Unwrap() error {
+	return e.err
+}
+
+// IsCollectionNotFound returns true if err is returned by a request of a collection resource that doesn't exist.
+func IsCollectionNotFound(err error) bool {
+	var e *CollectionResourceError
+	return errors.As(err, &e) && e.Collection != "" && e.ErrStatus.Reason == metav1.StatusReasonNotFound
+}
+
+// IsUnsupportedSearchLabel returns true if err indicates that the server rejected a search label,
+// e.g. the label is not supported by the storage layer or its value is invalid.
+func IsUnsupportedSearchLabel(err error) bool {
+	var e *CollectionResourceError
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.ErrStatus.Reason {
+	case metav1.StatusReasonBadRequest, metav1.StatusReasonInvalid:
+	default:
+		return false
+	}
+
+	for _, prefix := range searchLabelPrefixes {
+		if strings.Contains(e.ErrStatus.Message, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+func statusHandler(status metav1.Status) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status.APIVersion, status.Kind = "v1", "Status"
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(int(status.Code))
+		_ = json.NewEncoder(w).Encode(status)
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	testCase := []struct {
+		name                   string
+		handler                http.HandlerFunc
+		expectReason           metav1.StatusReason
+		expectNotFound         bool
+		expectUnsupportedLabel bool
+	}{
+		{
+			name: "not found",
+			handler: statusHandler(metav1.Status{
+				Status: metav1.StatusFailure, Code: http.StatusNotFound, Reason: metav1.StatusReasonNotFound,
+				Message: `collectionresources.clusterpedia.io "unknown" not found`,
+			}),
+			expectReason:   metav1.StatusReasonNotFound,
+			expectNotFound: true,
+		},
+		{
+			name: "unsupported search label",
+			handler: statusHandler(metav1.Status{
+				Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+				Message: "unsupported search label: internalstorage.clusterpedia.io/fuzzy-name",
+			}),
+			expectReason:           metav1.StatusReasonBadRequest,
+			expectUnsupportedLabel: true,
+		},
+		{
+			name: "internal error",
+			handler: statusHandler(metav1.Status{
+				Status: metav1.StatusFailure, Code: http.StatusInternalServerError, Reason: metav1.StatusReasonInternalError,
+				Message: "storage unavailable",
+			}),
+			expectReason: metav1.StatusReasonInternalError,
+		},
+		{
+			name: "non-status error body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "bad gateway", http.StatusBadGateway)
+			},
+			expectReason: metav1.StatusReasonInternalError,
+		},
+		{
+			name: "decode failure",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte("{not json"))
+			},
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			client, err := NewForConfig(&rest.Config{Host: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = client.CollectionResource().Fetch(context.TODO(), "workloads", metav1.ListOptions{}, nil)
+			if err == nil {
+				t.Fatal("Expect error, got nil")
+			}
+
+			var crErr *CollectionResourceError
+			if !errors.As(err, &crErr) {
+				t.Fatalf("Unexpect error type: %T", err)
+			}
+			if crErr.Collection != "workloads" {
+				t.Errorf("Unexpect collection: %s", crErr.Collection)
+			}
+			if !strings.HasPrefix(crErr.URL, server.URL+"/apis/clusterpedia.io/v1beta1/collectionresources/workloads") {
+				t.Errorf("Unexpect URL: %s", crErr.URL)
+			}
+			if crErr.ErrStatus.Reason != test.expectReason {
+				t.Errorf("Unexpect reason: %s, expect: %s", crErr.ErrStatus.Reason, test.expectReason)
+			}
+			if apierrors.ReasonForError(err) != test.expectReason {
+				t.Errorf("Unexpect apierrors reason: %s, expect: %s", apierrors.ReasonForError(err), test.expectReason)
+			}
+			if IsCollectionNotFound(err) != test.expectNotFound {
+				t.Errorf("Unexpect IsCollectionNotFound: %v", IsCollectionNotFound(err))
+			}
+			if IsUnsupportedSearchLabel(err) != test.expectUnsupportedLabel {
+				t.Errorf("Unexpect IsUnsupportedSearchLabel: %v", IsUnsupportedSearchLabel(err))
+			}
+		})
+	}
+}
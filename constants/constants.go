@@ -36,6 +36,7 @@ const (
 	SearchLabelOwnerUID       = "search.clusterpedia.io/owner-uid"
 	SearchLabelOwnerSeniority = "search.clusterpedia.io/owner-seniority"
 	SearchLabelOwnerName      = "search.clusterpedia.io/owner-name"
+	SearchLabelOwnerGR        = "search.clusterpedia.io/owner-gr"
 
 	SearchLabelWithContinue       = "search.clusterpedia.io/with-continue"
 	SearchLabelWithRemainingCount = "search.clusterpedia.io/with-remaining-count"
@@ -43,6 +44,9 @@ const (
 	SearchLabelLimit  = "search.clusterpedia.io/limit"
 	SearchLabelOffset = "search.clusterpedia.io/offset"
 
+	SearchLabelSince  = "search.clusterpedia.io/since"
+	SearchLabelBefore = "search.clusterpedia.io/before"
+
 	ShadowAnnotationClusterName          = "shadow.clusterpedia.io/cluster-name"
 	ShadowAnnotationGroupVersionResource = "shadow.clusterpedia.io/gvr"
 
@@ -19,19 +19,32 @@ package customclient
 import (
 	"context"
 
+	"github.com/clusterpedia-io/client-go/tools/builder"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
 )
 
 type Interface interface {
 	Resource(resource schema.GroupVersionResource) NamespaceableResourceInterface
-	// Debug 会输出请求的 HTTP URL
+	// Debug 返回一个 client 副本，它的请求会被 client.WithObserver 注册的 observer 标记为 observer.Event.Debug，
+	// 没有 observer 时请求 URL 由 slog 的默认 logger 输出。不会修改原 client，可以在多个 goroutine 共享的 client 上调用
 	Debug() Interface
 }
 
+// ResourceInterface 的 params 为请求的 query 参数，可以使用 builder.ParamsBuilder().Params() 构建，
+// WithParams 方法可以直接传入 builder.ParamsBuilder() 构建的参数，参数不合法时请求返回错误，params 为 nil 时不添加参数
 type ResourceInterface interface {
 	List(ctx context.Context, opts metav1.ListOptions, params map[string]string, obj runtime.Object) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions, params map[string]string, obj runtime.Object) error
+	// Watch 返回的事件对象如果是 client-go scheme 中已知的类型会被转换为对应的类型，否则为 *unstructured.Unstructured
+	Watch(ctx context.Context, opts metav1.ListOptions, params map[string]string) (watch.Interface, error)
+
+	ListWithParams(ctx context.Context, opts metav1.ListOptions, params builder.ParamsSource, obj runtime.Object) error
+	GetWithParams(ctx context.Context, name string, opts metav1.GetOptions, params builder.ParamsSource, obj runtime.Object) error
+	WatchWithParams(ctx context.Context, opts metav1.ListOptions, params builder.ParamsSource) (watch.Interface, error)
 }
 
 type NamespaceableResourceInterface interface {
@@ -0,0 +1,107 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/clusterpedia-io/client-go/tools/builder"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// WithRESTMapper 返回的 client 会通过 mapper 拒绝对集群级别资源调用 Namespace(),
+// mapper 可以使用 discovery.NewRESTMapperForConfig 构建
+func WithRESTMapper(c Interface, mapper meta.RESTMapper) Interface {
+	return &mapperClient{Interface: c, mapper: mapper}
+}
+
+type mapperClient struct {
+	Interface
+	mapper meta.RESTMapper
+}
+
+func (c *mapperClient) Debug() Interface {
+	return &mapperClient{Interface: c.Interface.Debug(), mapper: c.mapper}
+}
+
+func (c *mapperClient) Resource(resource schema.GroupVersionResource) NamespaceableResourceInterface {
+	return &mapperResourceClient{
+		NamespaceableResourceInterface: c.Interface.Resource(resource),
+		mapper:                         c.mapper,
+		resource:                       resource,
+	}
+}
+
+type mapperResourceClient struct {
+	NamespaceableResourceInterface
+	mapper   meta.RESTMapper
+	resource schema.GroupVersionResource
+}
+
+func (c *mapperResourceClient) Namespace(ns string) ResourceInterface {
+	if len(ns) == 0 {
+		return c.NamespaceableResourceInterface.Namespace(ns)
+	}
+
+	gvk, err := c.mapper.KindFor(c.resource)
+	if err != nil {
+		return &errorResourceClient{err: err}
+	}
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return &errorResourceClient{err: err}
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return &errorResourceClient{err: fmt.Errorf("%s is cluster-scoped, namespace %q is not allowed", c.resource.GroupResource(), ns)}
+	}
+	return c.NamespaceableResourceInterface.Namespace(ns)
+}
+
+// errorResourceClient returns the error for all of the requests
+type errorResourceClient struct {
+	err error
+}
+
+func (c *errorResourceClient) List(ctx context.Context, opts metav1.ListOptions, params map[string]string, obj runtime.Object) error {
+	return c.err
+}
+
+func (c *errorResourceClient) Get(ctx context.Context, name string, opts metav1.GetOptions, params map[string]string, obj runtime.Object) error {
+	return c.err
+}
+
+func (c *errorResourceClient) Watch(ctx context.Context, opts metav1.ListOptions, params map[string]string) (watch.Interface, error) {
+	return nil, c.err
+}
+
+func (c *errorResourceClient) ListWithParams(ctx context.Context, opts metav1.ListOptions, params builder.ParamsSource, obj runtime.Object) error {
+	return c.err
+}
+
+func (c *errorResourceClient) GetWithParams(ctx context.Context, name string, opts metav1.GetOptions, params builder.ParamsSource, obj runtime.Object) error {
+	return c.err
+}
+
+func (c *errorResourceClient) WatchWithParams(ctx context.Context, opts metav1.ListOptions, params builder.ParamsSource) (watch.Interface, error) {
+	return nil, c.err
+}
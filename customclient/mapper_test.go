@@ -0,0 +1,61 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customclient
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+func TestWithRESTMapper(t *testing.T) {
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	namespaces := schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(deployments.GroupVersion().WithKind("Deployment"), meta.RESTScopeNamespace)
+	mapper.Add(namespaces.GroupVersion().WithKind("Namespace"), meta.RESTScopeRoot)
+
+	c, err := NewForConfig(&rest.Config{Host: "http://127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = WithRESTMapper(c, mapper).Debug()
+
+	if _, ok := c.Resource(deployments).Namespace("default").(*errorResourceClient); ok {
+		t.Errorf("Expect namespaced resource is allowed in the namespace")
+	}
+	if _, ok := c.Resource(namespaces).Namespace("").(*errorResourceClient); ok {
+		t.Errorf("Expect cluster-scoped resource is allowed without namespace")
+	}
+
+	list := &unstructured.UnstructuredList{}
+	err = c.Resource(namespaces).Namespace("default").List(context.TODO(), metav1.ListOptions{}, nil, list)
+	if err == nil || err.Error() != `namespaces is cluster-scoped, namespace "default" is not allowed` {
+		t.Errorf("Unexpect error: %v", err)
+	}
+
+	unknown := schema.GroupVersionResource{Group: "example.io", Version: "v1", Resource: "foos"}
+	err = c.Resource(unknown).Namespace("default").List(context.TODO(), metav1.ListOptions{}, nil, list)
+	if !meta.IsNoMatchError(err) {
+		t.Errorf("Expect no match error, got: %v", err)
+	}
+}
@@ -18,14 +18,20 @@ package customclient
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/url"
 
 	"github.com/clusterpedia-io/client-go/client"
+	"github.com/clusterpedia-io/client-go/observer"
+	"github.com/clusterpedia-io/client-go/tools/builder"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 )
 
@@ -47,20 +53,30 @@ func ConfigFor(inConfig *rest.Config) *rest.Config {
 	return config
 }
 
-func NewForConfig(inConfig *rest.Config) (Interface, error) {
-	config, err := client.ConfigFor(inConfig)
+// NewForConfig returns the client of the clusterpedia resources, see client.Option for the options.
+func NewForConfig(inConfig *rest.Config, opts ...client.Option) (Interface, error) {
+	options := client.NewOptions(opts...)
+	config, err := options.ConfigFor(inConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	httpClient, err := rest.HTTPClientFor(config)
+	httpClient, err := options.HTTPClientFor(config)
 	if err != nil {
 		return nil, err
 	}
-	return NewForConfigAndClient(config, httpClient)
+	scheme := options.Scheme
+	if scheme == nil {
+		scheme = clientgoscheme.Scheme
+	}
+	return newForConfigAndClient(config, httpClient, scheme)
 }
 
 func NewForConfigAndClient(inConfig *rest.Config, h *http.Client) (Interface, error) {
+	return newForConfigAndClient(inConfig, h, clientgoscheme.Scheme)
+}
+
+func newForConfigAndClient(inConfig *rest.Config, h *http.Client, scheme *runtime.Scheme) (Interface, error) {
 	config := ConfigFor(inConfig)
 	// for serializing the options
 	config.GroupVersion = &schema.GroupVersion{}
@@ -70,7 +86,7 @@ func NewForConfigAndClient(inConfig *rest.Config, h *http.Client) (Interface, er
 	if err != nil {
 		return nil, err
 	}
-	return &restClient{client: rc}, nil
+	return &restClient{client: rc, scheme: scheme}, nil
 }
 
 type restResourceClient struct {
@@ -82,12 +98,17 @@ type restResourceClient struct {
 
 type restClient struct {
 	client    *rest.RESTClient
+	scheme    *runtime.Scheme
 	openDebug bool
 }
 
+// Debug returns a copy of the client whose requests are marked as observer.Event.Debug for the observers
+// registered by client.WithObserver, the URLs of the requests are logged by the default logger of slog
+// if there is no observer. c is not changed, so it is safe to call Debug on a client shared by goroutines.
 func (c *restClient) Debug() Interface {
-	c.openDebug = true
-	return c
+	ret := *c
+	ret.openDebug = true
+	return &ret
 }
 
 func (c *restClient) Resource(resource schema.GroupVersionResource) NamespaceableResourceInterface {
@@ -101,17 +122,120 @@ func (c *restResourceClient) Namespace(ns string) ResourceInterface {
 }
 
 func (c *restResourceClient) List(ctx context.Context, opts metav1.ListOptions, params map[string]string, obj runtime.Object) error {
+	return c.ListWithParams(ctx, opts, builder.Params(params), obj)
+}
+
+func (c *restResourceClient) Get(ctx context.Context, name string, opts metav1.GetOptions, params map[string]string, obj runtime.Object) error {
+	return c.GetWithParams(ctx, name, opts, builder.Params(params), obj)
+}
+
+func (c *restResourceClient) Watch(ctx context.Context, opts metav1.ListOptions, params map[string]string) (watch.Interface, error) {
+	return c.WatchWithParams(ctx, opts, builder.Params(params))
+}
+
+func (c *restResourceClient) ListWithParams(ctx context.Context, opts metav1.ListOptions, params builder.ParamsSource, obj runtime.Object) error {
+	req := rest.NewRequest(c.client.client)
+	req.AbsPath(c.makeURLSegments("")...).SpecificallyVersionedParams(&opts, parameterCodec, versionV1)
+	if err := setParams(req, params); err != nil {
+		return err
+	}
+
+	ctx = c.context(ctx)
+	err := req.Do(ctx).Into(obj)
+	c.debug(ctx, req)
+	return err
+}
+
+func (c *restResourceClient) GetWithParams(ctx context.Context, name string, opts metav1.GetOptions, params builder.ParamsSource, obj runtime.Object) error {
+	if len(name) == 0 {
+		return fmt.Errorf("name is required")
+	}
+	req := rest.NewRequest(c.client.client)
+	req.AbsPath(c.makeURLSegments(name)...).SpecificallyVersionedParams(&opts, parameterCodec, versionV1)
+	if err := setParams(req, params); err != nil {
+		return err
+	}
+
+	ctx = c.context(ctx)
+	err := req.Do(ctx).Into(obj)
+	c.debug(ctx, req)
+	return err
+}
+
+func (c *restResourceClient) WatchWithParams(ctx context.Context, opts metav1.ListOptions, params builder.ParamsSource) (watch.Interface, error) {
+	opts.Watch = true
 	req := rest.NewRequest(c.client.client)
 	req.AbsPath(c.makeURLSegments("")...).SpecificallyVersionedParams(&opts, parameterCodec, versionV1)
-	for key, value := range params {
+	if err := setParams(req, params); err != nil {
+		return nil, err
+	}
+
+	ctx = c.context(ctx)
+	w, err := req.Watch(ctx)
+	c.debug(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, c.client.convertEvent), nil
+}
+
+// setParams adds the params to the query of req, a nil params adds nothing.
+func setParams(req *rest.Request, params builder.ParamsSource) error {
+	if params == nil {
+		return nil
+	}
+	values, err := params.ParamsE()
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
 		req.Param(key, value)
 	}
+	return nil
+}
 
+// context marks the requests of the Debug client for the observers.
+func (c *restResourceClient) context(ctx context.Context) context.Context {
 	if c.openDebug {
+		return observer.WithDebug(ctx)
+	}
+	return ctx
+}
+
+// debug logs the URL of the request of the Debug client by the default logger of slog
+// if the request isn't seen by any observer.
+func (c *restResourceClient) debug(ctx context.Context, req *rest.Request) {
+	if c.openDebug && !observer.Observed(ctx) {
 		unescape, _ := url.QueryUnescape(req.URL().String())
-		slog.Debug("", slog.String("req.URL", unescape))
+		slog.DebugContext(ctx, "customclient request", slog.String("req.URL", unescape))
+	}
+}
+
+// convertEvent converts the unstructured object of the event to the typed object if its kind is known by the scheme.
+func (c *restClient) convertEvent(event watch.Event) (watch.Event, bool) {
+	u, ok := event.Object.(*unstructured.Unstructured)
+	if !ok {
+		return event, true
+	}
+
+	gvk := u.GroupVersionKind()
+	if !c.scheme.Recognizes(gvk) {
+		return event, true
+	}
+	obj, err := c.scheme.New(gvk)
+	if err != nil {
+		return event, true
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), obj); err != nil {
+		return watch.Event{Type: watch.Error, Object: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: fmt.Sprintf("failed to convert %s to typed object: %v", gvk, err),
+			Reason:  metav1.StatusReasonInternalError,
+			Code:    http.StatusInternalServerError,
+		}}, true
 	}
-	return req.Do(ctx).Into(obj)
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	return watch.Event{Type: event.Type, Object: obj}, true
 }
 
 func (c *restResourceClient) makeURLSegments(name string) []string {
@@ -0,0 +1,212 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/clusterpedia-io/client-go/client"
+	"github.com/clusterpedia-io/client-go/constants"
+	"github.com/clusterpedia-io/client-go/observer"
+	"github.com/clusterpedia-io/client-go/tools/builder"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+)
+
+func TestGetAndWatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case constants.ClusterPediaAPIPath + "/apis/apps/v1/namespaces/default/deployments/nginx":
+			fmt.Fprint(w, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"nginx","namespace":"default"}}`)
+		case constants.ClusterPediaAPIPath + "/apis/apps/v1/namespaces/default/deployments":
+			if r.URL.Query().Get("watch") != "true" || r.URL.Query().Get("clusters") != "cluster-1" {
+				http.Error(w, "unexpected query", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintln(w, `{"type":"ADDED","object":{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"nginx","namespace":"default"}}}`)
+			fmt.Fprintln(w, `{"type":"MODIFIED","object":{"apiVersion":"example.io/v1","kind":"Foo","metadata":{"name":"foo","namespace":"default"}}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deploy := &appsv1.Deployment{}
+	deployments := c.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}).Namespace("default")
+	if err := deployments.Get(context.TODO(), "nginx", metav1.GetOptions{}, nil, deploy); err != nil {
+		t.Fatal(err)
+	}
+	if deploy.Name != "nginx" {
+		t.Errorf("Unexpect deployment: %v", deploy.Name)
+	}
+
+	w, err := deployments.Watch(context.TODO(), metav1.ListOptions{}, map[string]string{"clusters": "cluster-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	event := <-w.ResultChan()
+	if obj, ok := event.Object.(*appsv1.Deployment); event.Type != watch.Added || !ok || obj.Name != "nginx" {
+		t.Errorf("Unexpect event: %s %#v", event.Type, event.Object)
+	}
+	event = <-w.ResultChan()
+	if obj, ok := event.Object.(*unstructured.Unstructured); event.Type != watch.Modified || !ok || obj.GetName() != "foo" {
+		t.Errorf("Unexpect event: %s %#v", event.Type, event.Object)
+	}
+}
+
+func TestParamsBuilder(t *testing.T) {
+	var requests int
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		query = r.URL.Query().Get("onlyMetadata")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"apiVersion":"apps/v1","kind":"DeploymentList","metadata":{},"items":[]}`)
+	}))
+	defer server.Close()
+
+	c, err := NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployments := c.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"})
+
+	if err := deployments.ListWithParams(context.TODO(), metav1.ListOptions{}, builder.ParamsBuilder().OnlyMetadata(), &appsv1.DeploymentList{}); err != nil {
+		t.Fatal(err)
+	}
+	if query != "true" {
+		t.Errorf("Unexpect onlyMetadata: %q", query)
+	}
+
+	err = deployments.ListWithParams(context.TODO(), metav1.ListOptions{}, builder.ParamsBuilder().Clusters(""), &appsv1.DeploymentList{})
+	if err == nil {
+		t.Error("Expect the error of the invalid params")
+	}
+	if requests != 1 {
+		t.Errorf("Unexpect requests: %d", requests)
+	}
+}
+
+func TestDebugLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"apiVersion":"apps/v1","kind":"DeploymentList","metadata":{},"items":[]}`)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	testCase := []struct {
+		name      string
+		opts      []client.Option
+		expectLog bool
+	}{
+		{"without observer", nil, true},
+		{"with observer", []client.Option{client.WithObserver(&observer.Recorder{})}, false},
+	}
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			buf.Reset()
+			c, err := NewForConfig(&rest.Config{Host: server.URL}, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			deployments := c.Debug().Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"})
+			if err := deployments.List(context.TODO(), metav1.ListOptions{}, nil, &appsv1.DeploymentList{}); err != nil {
+				t.Fatal(err)
+			}
+			if logged := strings.Contains(buf.String(), "/apis/apps/v1/deployments"); logged != test.expectLog {
+				t.Errorf("Unexpect log: %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestDebugConcurrently(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"apiVersion":"apps/v1","kind":"DeploymentList","metadata":{},"items":[]}`)
+	}))
+	defer server.Close()
+
+	recorder := &observer.Recorder{}
+	c, err := NewForConfig(&rest.Config{Host: server.URL}, client.WithObserver(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			list := &appsv1.DeploymentList{}
+			if err := c.Debug().Resource(deployments).Namespace("default").List(context.TODO(), metav1.ListOptions{}, nil, list); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			list := &appsv1.DeploymentList{}
+			if err := c.Resource(deployments).List(context.TODO(), metav1.ListOptions{}, nil, list); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var debug int
+	for _, event := range recorder.Events() {
+		if event.Debug {
+			debug++
+		}
+	}
+	if events := len(recorder.Events()); events != 20 || debug != 10 {
+		t.Errorf("Unexpect events: %d, debug events: %d", events, debug)
+	}
+
+	if c.(*restClient).openDebug {
+		t.Errorf("Expect Debug doesn't change the shared client")
+	}
+	if !c.Debug().(*restClient).openDebug {
+		t.Errorf("Expect the client returned by Debug marks the requests")
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	client "github.com/clusterpedia-io/client-go/client"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+)
+
+// NewForConfig returns the discovery client of the resources synced from all of the clusters,
+// the discovery is cached in memory until Invalidate is called.
+func NewForConfig(cfg *rest.Config, opts ...client.Option) (discovery.CachedDiscoveryInterface, error) {
+	options := client.NewOptions(opts...)
+	config, err := options.ConfigFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := options.HTTPClientFor(config)
+	if err != nil {
+		return nil, err
+	}
+
+	dc, err := discovery.NewDiscoveryClientForConfigAndClient(config, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return memory.NewMemCacheClient(dc), nil
+}
+
+// NewClusterForConfig returns the discovery client of the resources synced from the cluster like NewForConfig.
+func NewClusterForConfig(cfg *rest.Config, cluster string, opts ...client.Option) (discovery.CachedDiscoveryInterface, error) {
+	return NewForConfig(cfg, append(opts[:len(opts):len(opts)], client.WithCluster(cluster))...)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+// RESTMapper maps the kinds, resources and short names to the resources synced by clusterpedia,
+// the mappings are discovered lazily and rediscovered when a kind or resource isn't found.
+type RESTMapper struct {
+	meta.RESTMapper
+}
+
+var _ meta.ResettableRESTMapper = &RESTMapper{}
+
+func NewRESTMapper(dc discovery.CachedDiscoveryInterface) *RESTMapper {
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(dc)
+	return &RESTMapper{RESTMapper: restmapper.NewShortcutExpander(mapper, dc)}
+}
+
+// NewRESTMapperForConfig returns the RESTMapper of the resources synced from all of the clusters,
+// or from the cluster if it is specified.
+func NewRESTMapperForConfig(cfg *rest.Config, cluster ...string) (*RESTMapper, error) {
+	var dc discovery.CachedDiscoveryInterface
+	var err error
+	if len(cluster) == 1 {
+		dc, err = NewClusterForConfig(cfg, cluster[0])
+	} else {
+		dc, err = NewForConfig(cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewRESTMapper(dc), nil
+}
+
+// ForKind returns the mapping of the kind, the kind can be qualified by the group and version,
+// e.g. "Deployment", "Deployment.apps" or "Deployment.v1.apps".
+// The preferred version is used if the version is not specified.
+func (m *RESTMapper) ForKind(kind string) (*meta.RESTMapping, error) {
+	gvk, gk := schema.ParseKindArg(kind)
+	if gvk != nil {
+		if mapping, err := m.RESTMapping(gvk.GroupKind(), gvk.Version); err == nil {
+			return mapping, nil
+		}
+	}
+	if gk.Group != "" {
+		return m.RESTMapping(gk)
+	}
+
+	// the singular name of a resource is the lowercase kind
+	return m.ForResource(strings.ToLower(gk.Kind))
+}
+
+// ForResource returns the mapping of the resource, the resource can be the plural, singular or short name
+// qualified by the group and version, e.g. "deployments", "deploy", "deployments.apps" or "deployments.v1.apps".
+// The preferred version is used if the version is not specified.
+func (m *RESTMapper) ForResource(resource string) (*meta.RESTMapping, error) {
+	gvr, gr := schema.ParseResourceArg(resource)
+	if gvr != nil {
+		if gvk, err := m.KindFor(*gvr); err == nil {
+			return m.RESTMapping(gvk.GroupKind(), gvk.Version)
+		}
+	}
+
+	gvk, err := m.KindFor(gr.WithVersion(""))
+	if err != nil {
+		return nil, err
+	}
+	return m.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
+
+// IsNamespaced returns true if the resource is namespaced.
+func (m *RESTMapper) IsNamespaced(gvr schema.GroupVersionResource) (bool, error) {
+	gvk, err := m.KindFor(gvr)
+	if err != nil {
+		return false, err
+	}
+	mapping, err := m.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false, err
+	}
+	return mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
+
+// Reset resets the discovered mappings, the mappings are rediscovered by the next call.
+func (m *RESTMapper) Reset() {
+	meta.MaybeResetRESTMapper(m.RESTMapper)
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"testing"
+
+	"github.com/clusterpedia-io/client-go/tools/clusterpediatest"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	deployments = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	pods        = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	namespaces  = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+)
+
+func newTestServer(t *testing.T) *clusterpediatest.Server {
+	server := clusterpediatest.NewTestServer(t)
+	objects := map[string][]runtime.Object{
+		"cluster-1": {
+			&appsv1.Deployment{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+				ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+			},
+			&corev1.Pod{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+				ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+			},
+			&corev1.Namespace{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+				ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			},
+		},
+		"cluster-2": {
+			&appsv1.Deployment{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+				ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+			},
+		},
+	}
+	for cluster, objs := range objects {
+		if err := server.AddObjects(cluster, objs...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return server
+}
+
+func TestRESTMapper(t *testing.T) {
+	server := newTestServer(t)
+	mapper, err := NewRESTMapperForConfig(server.RESTConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCase := []struct {
+		name            string
+		mapping         func() (*meta.RESTMapping, error)
+		expectResource  schema.GroupVersionResource
+		expectNamespace bool
+	}{
+		{"kind", func() (*meta.RESTMapping, error) { return mapper.ForKind("Deployment") }, deployments, true},
+		{"kind with group", func() (*meta.RESTMapping, error) { return mapper.ForKind("Deployment.apps") }, deployments, true},
+		{"kind with version", func() (*meta.RESTMapping, error) { return mapper.ForKind("Deployment.v1.apps") }, deployments, true},
+		{"cluster-scoped kind", func() (*meta.RESTMapping, error) { return mapper.ForKind("Namespace") }, namespaces, false},
+		{"resource", func() (*meta.RESTMapping, error) { return mapper.ForResource("deployments") }, deployments, true},
+		{"singular", func() (*meta.RESTMapping, error) { return mapper.ForResource("pod") }, pods, true},
+		{"short name", func() (*meta.RESTMapping, error) { return mapper.ForResource("deploy") }, deployments, true},
+		{"short name of core group", func() (*meta.RESTMapping, error) { return mapper.ForResource("ns") }, namespaces, false},
+		{"resource with group", func() (*meta.RESTMapping, error) { return mapper.ForResource("deployments.apps") }, deployments, true},
+		{"resource with version", func() (*meta.RESTMapping, error) { return mapper.ForResource("deployments.v1.apps") }, deployments, true},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			mapping, err := test.mapping()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mapping.Resource != test.expectResource {
+				t.Errorf("Unexpect resource: %s, expect: %s", mapping.Resource, test.expectResource)
+			}
+			if namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace; namespaced != test.expectNamespace {
+				t.Errorf("Unexpect namespaced: %v, expect: %v", namespaced, test.expectNamespace)
+			}
+
+			namespaced, err := mapper.IsNamespaced(mapping.Resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if namespaced != test.expectNamespace {
+				t.Errorf("Unexpect IsNamespaced: %v, expect: %v", namespaced, test.expectNamespace)
+			}
+		})
+	}
+}
+
+func TestClusterRESTMapper(t *testing.T) {
+	server := newTestServer(t)
+	mapper, err := NewRESTMapperForConfig(server.RESTConfig(), "cluster-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mapping, err := mapper.ForKind("Deployment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping.Resource != deployments {
+		t.Errorf("Unexpect resource: %s", mapping.Resource)
+	}
+
+	// pods are not synced from cluster-2
+	if _, err := mapper.ForResource("po"); !meta.IsNoMatchError(err) {
+		t.Errorf("Expect no match error, got: %v", err)
+	}
+	if _, err := mapper.ForKind("Pod"); !meta.IsNoMatchError(err) {
+		t.Errorf("Expect no match error, got: %v", err)
+	}
+}
@@ -22,30 +22,27 @@ import (
 	"k8s.io/client-go/rest"
 )
 
-func NewForConfig(cfg *rest.Config) (dynamic.Interface, error) {
-	kubeconfig, err := client.ConfigFor(cfg)
+// NewForConfig returns the dynamic client of the clusterpedia resources, see client.Option for the options.
+func NewForConfig(cfg *rest.Config, opts ...client.Option) (dynamic.Interface, error) {
+	options := client.NewOptions(opts...)
+	kubeconfig, err := options.ConfigFor(cfg)
 	if err != nil {
 		return nil, err
 	}
-
-	dc, err := dynamic.NewForConfig(kubeconfig)
-	if err != nil {
-		return nil, err
-	}
-
-	return dc, nil
-}
-
-func NewClusterForConfig(cfg *rest.Config, cluster string) (dynamic.Interface, error) {
-	kubeconfig, err := client.ClusterConfigFor(cfg, cluster)
+	httpClient, err := options.HTTPClientFor(kubeconfig)
 	if err != nil {
 		return nil, err
 	}
 
-	dc, err := dynamic.NewForConfig(kubeconfig)
+	dc, err := dynamic.NewForConfigAndClient(kubeconfig, httpClient)
 	if err != nil {
 		return nil, err
 	}
 
 	return dc, nil
 }
+
+// NewClusterForConfig returns the dynamic client of the resources of the cluster.
+func NewClusterForConfig(cfg *rest.Config, cluster string, opts ...client.Option) (dynamic.Interface, error) {
+	return NewForConfig(cfg, append(opts[:len(opts):len(opts)], client.WithCluster(cluster))...)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamic
+
+import (
+	"net/http"
+
+	client "github.com/clusterpedia-io/client-go/client"
+	"github.com/clusterpedia-io/client-go/pkg/lru"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// ClusterSet is the dynamic client of the resources aggregated from all of the clusters,
+// Cluster returns the dynamic client of a single cluster.
+//
+// The clients of all clusters share the http.Client and the rate limiter of the ClusterSet like client.ClusterSet.
+type ClusterSet struct {
+	dynamic.Interface
+
+	config   *rest.Config
+	options  *client.Options
+	clusters *lru.Cache[string, dynamic.Interface]
+}
+
+// NewClusterSetForConfig creates a ClusterSet, size bounds the number of cached cluster clients,
+// a size <= 0 uses client.DefaultClusterCacheSize. The options are applied to the clients of all clusters,
+// except the cluster option.
+func NewClusterSetForConfig(cfg *rest.Config, size int, opts ...client.Option) (*ClusterSet, error) {
+	options := client.NewOptions(opts...)
+	options.Cluster = ""
+
+	config, err := options.ConfigFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// the clients of the clusters share the rate limiter, so the clusters don't multiply the QPS
+	cfg = rest.CopyConfig(cfg)
+	cfg.RateLimiter = client.SharedRateLimiter(config)
+	config.RateLimiter = cfg.RateLimiter
+	if options.HTTPClient, err = options.HTTPClientFor(config); err != nil {
+		return nil, err
+	}
+	dc, err := dynamic.NewForConfigAndClient(config, options.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if size <= 0 {
+		size = client.DefaultClusterCacheSize
+	}
+	return &ClusterSet{
+		Interface: dc,
+		config:    cfg,
+		options:   options,
+		clusters:  lru.New[string, dynamic.Interface](size),
+	}, nil
+}
+
+// NewClusterSetForConfigAndClient creates a ClusterSet whose clients share the httpClient.
+func NewClusterSetForConfigAndClient(cfg *rest.Config, httpClient *http.Client, size int) (*ClusterSet, error) {
+	return NewClusterSetForConfig(cfg, size, client.WithHTTPClient(httpClient))
+}
+
+// Cluster returns the dynamic client of the resources synced from the cluster.
+func (s *ClusterSet) Cluster(cluster string) (dynamic.Interface, error) {
+	if dc, ok := s.clusters.Get(cluster); ok {
+		return dc, nil
+	}
+
+	config, err := s.options.ClusterConfigFor(s.config, cluster)
+	if err != nil {
+		return nil, err
+	}
+	dc, err := dynamic.NewForConfigAndClient(config, s.options.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+	s.clusters.Add(cluster, dc)
+	return dc, nil
+}
+
+// HTTPClient returns the http.Client shared by the clients of the ClusterSet.
+func (s *ClusterSet) HTTPClient() *http.Client {
+	return s.options.HTTPClient
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/clusterpedia-io/client-go/tools/clusterpediatest"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestClusterSet(t *testing.T) {
+	server := clusterpediatest.NewTestServer(t)
+	for _, cluster := range []string{"cluster-1", "cluster-2"} {
+		if err := server.AddObjects(cluster, &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx-" + cluster, Namespace: "default"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	set, err := NewClusterSetForConfig(server.RESTConfig(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	list, err := set.Resource(deployments).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 2 {
+		t.Errorf("Unexpect deployments of all clusters: %d", len(list.Items))
+	}
+
+	dc, err := set.Cluster("cluster-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err = dc.Resource(deployments).Namespace("default").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].GetName() != "nginx-cluster-2" {
+		t.Errorf("Unexpect deployments of cluster-2: %v", list.Items)
+	}
+	if cached, _ := set.Cluster("cluster-2"); cached != dc {
+		t.Errorf("Expect the client of cluster-2 is cached")
+	}
+}
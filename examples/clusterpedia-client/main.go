@@ -23,12 +23,11 @@ import (
 	"os"
 
 	"github.com/clusterpedia-io/client-go/clusterpediaclient"
+	"github.com/clusterpedia-io/client-go/clusterpediaclient/v1beta1"
 	"github.com/clusterpedia-io/client-go/tools/builder"
 
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
@@ -75,34 +74,32 @@ func main() {
 	}
 
 	options = builder.ListOptionsBuilder().Namespaces(metav1.NamespaceDefault).Options()
-	resources, err = cc.PediaClusterV1beta1().CollectionResource().Fetch(context.TODO(), "workloads", options, map[string]string{
-		"clusters":     "k3s-2",
-		"onlyMetadata": "true",
-	})
+	resources, err = cc.PediaClusterV1beta1().CollectionResource().FetchWithParams(context.TODO(), "workloads", options, builder.ParamsBuilder().
+		Clusters("k3s-2").
+		OnlyMetadata())
 	if err != nil {
 		panic(err)
 	}
 
-	for _, item := range resources.Items {
-		deploy := &appsv1.Deployment{}
-		unstructured.UnstructuredJSONScheme.Decode(item.Raw, &schema.GroupVersionKind{
-			Group:   "apps",
-			Version: "v1",
-			Kind:    "Deployment",
-		}, deploy)
-		//slog.Debug("resource info", slog.String("info", string(item.Raw)))
+	decoded, err := v1beta1.NewDecoder(nil).Decode(resources)
+	if err != nil {
+		panic(err)
+	}
+	for _, item := range decoded.Items[appsv1.SchemeGroupVersion.WithKind("Deployment")] {
+		deploy, ok := item.Object.(*appsv1.Deployment)
+		if !ok {
+			continue
+		}
 		slog.Debug("deploy",
+			slog.String("cluster", item.Cluster),
 			slog.String("namespace/name", fmt.Sprintf("%v/%v", deploy.Namespace, deploy.Name)))
-		//fmt.Printf("resource info: %v\n", string(item.Raw))
-		_ = item
 	}
 
 	options = builder.ListOptionsBuilder().Namespaces(metav1.NamespaceDefault).Options()
-	resources, err = cc.PediaClusterV1beta1().CollectionResource().Fetch(context.TODO(), "any", options, map[string]string{
-		"onlyMetadata": "true",
-		"groups":       "apps",
-		//"resources":    "",
-	})
+	resources, err = cc.PediaClusterV1beta1().CollectionResource().FetchWithParams(context.TODO(), "any", options, builder.ParamsBuilder().
+		OnlyMetadata().
+		// Resources(schema.GroupVersionResource{Version: "v1", Resource: "pods"}).
+		Groups(appsv1.SchemeGroupVersion))
 	if err != nil {
 		panic(err)
 	}
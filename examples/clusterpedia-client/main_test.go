@@ -12,25 +12,24 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/clusterpedia-io/client-go/clusterpediaclient"
 	"github.com/clusterpedia-io/client-go/tools/builder"
+	"github.com/clusterpedia-io/client-go/tools/clusterpediatest"
 )
 
 var cc *clusterpediaclient.ClusterpediaClient
 
-func Init() {
+func Init(t *testing.T) {
 	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		//AddSource: true,
 		Level: slog.LevelDebug,
 	})))
 
-	restConfig, err := ctrl.GetConfig()
-	if err != nil {
-		panic(err)
-	}
-	c, err := clusterpediaclient.NewForConfig(restConfig)
+	// the fake clusterpedia server serves the fixtures in testdata,
+	// use ctrl.GetConfig() to try the examples against a real clusterpedia.
+	server := clusterpediatest.NewTestServer(t, "testdata/resources.yaml")
+	c, err := clusterpediaclient.NewForConfig(server.RESTConfig())
 	if err != nil {
 		panic(err)
 	}
@@ -38,7 +37,7 @@ func Init() {
 }
 
 func TestListCollectionResource(t *testing.T) {
-	Init()
+	Init(t)
 
 	// https://kubernetes.docker.internal:6443/apis/clusterpedia.io/v1beta1/collectionresources
 	collectionResource, err := cc.PediaClusterV1beta1().CollectionResource().List(context.TODO(), metav1.ListOptions{})
@@ -55,7 +54,7 @@ func TestListCollectionResource(t *testing.T) {
 }
 
 func TestListWorkloads(t *testing.T) {
-	Init()
+	Init(t)
 
 	// build listOptions
 	// 只查询 default 这个 namespace 下的资源
@@ -114,7 +113,7 @@ func TestListWorkloads(t *testing.T) {
 }
 
 func TestListKubeResources(t *testing.T) {
-	Init()
+	Init(t)
 
 	// build listOptions
 	// 只查询 default 这个 namespace 下的资源
@@ -140,12 +139,12 @@ func TestListKubeResources(t *testing.T) {
 }
 
 func TestListAny(t *testing.T) {
-	Init()
+	Init(t)
 
 	options := builder.ListOptionsBuilder().
 		Namespaces(metav1.NamespaceDefault).
 		Limit(10).
-		Clusters("k3s2").
+		Clusters("k3s-2").
 		Options()
 
 	resources, err := cc.PediaClusterV1beta1().Debug().CollectionResource().Fetch(context.TODO(), "any", options, map[string]string{
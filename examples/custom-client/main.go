@@ -29,7 +29,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
 
+	"github.com/clusterpedia-io/client-go/client"
 	"github.com/clusterpedia-io/client-go/customclient"
+	"github.com/clusterpedia-io/client-go/observer"
 	"github.com/clusterpedia-io/client-go/tools/builder"
 )
 
@@ -43,7 +45,8 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to init config: %v", err)
 	}
-	customClient, err := customclient.NewForConfig(config)
+	// the requests of the Debug clients are logged at the info level by the slog observer
+	customClient, err := customclient.NewForConfig(config, client.WithObserver(observer.Slog(slog.Default())))
 	if err != nil {
 		log.Fatalf("failed to init customClient: %v", err)
 	}
@@ -87,7 +90,7 @@ func main() {
 		Debug().
 		Resource(schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}).
 		Namespace(metav1.NamespaceDefault).
-		List(context.TODO(), options, map[string]string{"onlyMetadata": "true"}, pods); err != nil {
+		ListWithParams(context.TODO(), options, builder.ParamsBuilder().OnlyMetadata(), pods); err != nil {
 		panic(err)
 	}
 	if pods.RemainingItemCount != nil {
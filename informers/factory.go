@@ -0,0 +1,167 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/clusterpedia-io/client-go/tools/builder"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SharedInformerFactory provides shared informers for resources searched through clusterpedia.
+type SharedInformerFactory interface {
+	// Start initializes all requested informers.
+	Start(stopCh <-chan struct{})
+	// ForResource returns the informer of the resource.
+	ForResource(gvr schema.GroupVersionResource) GenericInformer
+	// WaitForCacheSync waits for all started informers' cache were synced.
+	WaitForCacheSync(stopCh <-chan struct{}) map[schema.GroupVersionResource]bool
+	// Shutdown waits for all started informers to stop, the stop channel passed to Start must be closed first.
+	Shutdown()
+}
+
+// GenericInformer gives access to an informer and its lister.
+type GenericInformer interface {
+	Informer() Informer
+	Lister() Lister
+}
+
+// NewSharedInformerFactory constructs a factory for all clusters and namespaces,
+// client must be the aggregated dynamic client created by dynamic.NewForConfig of this module.
+func NewSharedInformerFactory(client dynamic.Interface, defaultResync time.Duration) SharedInformerFactory {
+	return NewFilteredSharedInformerFactory(client, defaultResync, nil)
+}
+
+// NewFilteredSharedInformerFactory constructs a factory whose informers only search the
+// objects matched by options, e.g. builder.ListOptionsBuilder().Clusters("cluster-1").
+// Limit and Offset of options are ignored, the informers always list all of the pages.
+func NewFilteredSharedInformerFactory(client dynamic.Interface, defaultResync time.Duration, options builder.ListOptionsInterface) SharedInformerFactory {
+	var listOptions metav1.ListOptions
+	if options != nil {
+		listOptions = options.Options()
+		listOptions.Limit, listOptions.Continue = 0, ""
+	}
+	return &sharedInformerFactory{
+		client:           client,
+		defaultResync:    defaultResync,
+		options:          listOptions,
+		informers:        make(map[schema.GroupVersionResource]GenericInformer),
+		startedInformers: make(map[schema.GroupVersionResource]bool),
+	}
+}
+
+type sharedInformerFactory struct {
+	client        dynamic.Interface
+	defaultResync time.Duration
+	options       metav1.ListOptions
+
+	lock      sync.Mutex
+	informers map[schema.GroupVersionResource]GenericInformer
+	// startedInformers is used for tracking which informers have been started.
+	startedInformers map[schema.GroupVersionResource]bool
+
+	wg           sync.WaitGroup
+	shuttingDown bool
+}
+
+func (f *sharedInformerFactory) ForResource(gvr schema.GroupVersionResource) GenericInformer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	informer, exists := f.informers[gvr]
+	if exists {
+		return informer
+	}
+
+	informer = &genericInformer{
+		gvr:      gvr,
+		informer: NewInformer(f.client, gvr, f.defaultResync, f.options),
+	}
+	f.informers[gvr] = informer
+	return informer
+}
+
+func (f *sharedInformerFactory) Start(stopCh <-chan struct{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.shuttingDown {
+		return
+	}
+
+	for gvr, informer := range f.informers {
+		if f.startedInformers[gvr] {
+			continue
+		}
+
+		f.wg.Add(1)
+		informer := informer.Informer()
+		go func() {
+			defer f.wg.Done()
+			informer.Run(stopCh)
+		}()
+		f.startedInformers[gvr] = true
+	}
+}
+
+func (f *sharedInformerFactory) WaitForCacheSync(stopCh <-chan struct{}) map[schema.GroupVersionResource]bool {
+	informers := func() map[schema.GroupVersionResource]Informer {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		informers := make(map[schema.GroupVersionResource]Informer)
+		for gvr, informer := range f.informers {
+			if f.startedInformers[gvr] {
+				informers[gvr] = informer.Informer()
+			}
+		}
+		return informers
+	}()
+
+	res := make(map[schema.GroupVersionResource]bool, len(informers))
+	for gvr, informer := range informers {
+		res[gvr] = cache.WaitForCacheSync(stopCh, informer.HasSynced)
+	}
+	return res
+}
+
+func (f *sharedInformerFactory) Shutdown() {
+	defer f.wg.Wait()
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.shuttingDown = true
+}
+
+type genericInformer struct {
+	gvr      schema.GroupVersionResource
+	informer Informer
+}
+
+func (i *genericInformer) Informer() Informer {
+	return i.informer
+}
+
+func (i *genericInformer) Lister() Lister {
+	return NewLister(i.informer.GetIndexer(), i.gvr)
+}
@@ -0,0 +1,358 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/clusterpedia-io/client-go/constants"
+	"github.com/clusterpedia-io/client-go/tools/pager"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// ClusterIndex indexes objects by the cluster they come from.
+	ClusterIndex = "cluster"
+	// ClusterNamespaceIndex indexes objects by "<cluster>/<namespace>".
+	ClusterNamespaceIndex = "cluster-namespace"
+
+	// DefaultRelistPeriod is the relist period used when the server doesn't support watch
+	// and the informer is created without a resync period.
+	DefaultRelistPeriod = time.Minute
+
+	listPageSize = 500
+)
+
+// Informer is an informer of a resource searched through clusterpedia.
+// Unlike cache.SharedIndexInformer, objects are keyed by KeyFunc, so the same
+// namespace/name in different clusters are different objects.
+type Informer interface {
+	// AddEventHandler adds a handler, the existing objects are delivered to
+	// the handler as add events if the informer has already been started.
+	AddEventHandler(handler cache.ResourceEventHandler)
+	GetIndexer() cache.Indexer
+	HasSynced() bool
+	LastSyncResourceVersion() string
+	Run(stopCh <-chan struct{})
+}
+
+// KeyFunc returns "<cluster>/<namespace>/<name>" for namespaced objects and
+// "<cluster>/<name>" for cluster scoped objects.
+func KeyFunc(obj interface{}) (string, error) {
+	if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return d.Key, nil
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", err
+	}
+	return Key(clusterOf(accessor), accessor.GetNamespace(), accessor.GetName()), nil
+}
+
+// Key returns the key of an object in the informer's indexer.
+func Key(cluster, namespace, name string) string {
+	if len(namespace) == 0 {
+		return cluster + "/" + name
+	}
+	return cluster + "/" + namespace + "/" + name
+}
+
+// ClusterIndexFunc is the index function of ClusterIndex.
+func ClusterIndexFunc(obj interface{}) ([]string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	return []string{clusterOf(accessor)}, nil
+}
+
+// ClusterNamespaceIndexFunc is the index function of ClusterNamespaceIndex.
+func ClusterNamespaceIndexFunc(obj interface{}) ([]string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	return []string{clusterOf(accessor) + "/" + accessor.GetNamespace()}, nil
+}
+
+func clusterOf(obj metav1.Object) string {
+	return obj.GetAnnotations()[constants.ShadowAnnotationClusterName]
+}
+
+// NewInformer creates an informer of the resource searched with options.
+//
+// The informer watches the resource if the server allows it, otherwise the resource
+// is re-listed every resyncPeriod, or DefaultRelistPeriod if resyncPeriod is 0.
+func NewInformer(client dynamic.Interface, gvr schema.GroupVersionResource, resyncPeriod time.Duration, options metav1.ListOptions) Informer {
+	relistPeriod := resyncPeriod
+	if relistPeriod <= 0 {
+		relistPeriod = DefaultRelistPeriod
+	}
+
+	return &informer{
+		gvr:          gvr,
+		client:       client.Resource(gvr),
+		options:      options,
+		relistPeriod: relistPeriod,
+		resyncPeriod: resyncPeriod,
+		indexer: cache.NewIndexer(KeyFunc, cache.Indexers{
+			ClusterIndex:          ClusterIndexFunc,
+			cache.NamespaceIndex:  cache.MetaNamespaceIndexFunc,
+			ClusterNamespaceIndex: ClusterNamespaceIndexFunc,
+		}),
+	}
+}
+
+type informer struct {
+	gvr          schema.GroupVersionResource
+	client       dynamic.NamespaceableResourceInterface
+	options      metav1.ListOptions
+	relistPeriod time.Duration
+	resyncPeriod time.Duration
+	indexer      cache.Indexer
+
+	handlerLock sync.RWMutex
+	handlers    []cache.ResourceEventHandler
+
+	controllerLock sync.RWMutex
+	controller     cache.Controller
+}
+
+func (i *informer) AddEventHandler(handler cache.ResourceEventHandler) {
+	i.handlerLock.Lock()
+	defer i.handlerLock.Unlock()
+
+	i.handlers = append(i.handlers, handler)
+	if i.started() {
+		for _, obj := range i.indexer.List() {
+			handler.OnAdd(obj, true)
+		}
+	}
+}
+
+func (i *informer) GetIndexer() cache.Indexer {
+	return i.indexer
+}
+
+func (i *informer) started() bool {
+	i.controllerLock.RLock()
+	defer i.controllerLock.RUnlock()
+	return i.controller != nil
+}
+
+func (i *informer) HasSynced() bool {
+	i.controllerLock.RLock()
+	defer i.controllerLock.RUnlock()
+
+	if i.controller == nil {
+		return false
+	}
+	return i.controller.HasSynced()
+}
+
+func (i *informer) LastSyncResourceVersion() string {
+	i.controllerLock.RLock()
+	defer i.controllerLock.RUnlock()
+
+	if i.controller == nil {
+		return ""
+	}
+	return i.controller.LastSyncResourceVersion()
+}
+
+func (i *informer) Run(stopCh <-chan struct{}) {
+	fifo := cache.NewDeltaFIFOWithOptions(cache.DeltaFIFOOptions{
+		KeyFunction:           KeyFunc,
+		KnownObjects:          i.indexer,
+		EmitDeltaTypeReplaced: true,
+	})
+
+	i.controllerLock.Lock()
+	if i.controller != nil {
+		i.controllerLock.Unlock()
+		return
+	}
+	i.controller = cache.New(&cache.Config{
+		Queue:             fifo,
+		ListerWatcher:     newListWatch(wait.ContextForChannel(stopCh), i.client, i.options, i.relistPeriod),
+		ObjectType:        &unstructured.Unstructured{},
+		ObjectDescription: i.gvr.String(),
+		FullResyncPeriod:  i.resyncPeriod,
+		RetryOnError:      false,
+		Process:           i.process,
+	})
+	i.controllerLock.Unlock()
+
+	i.controller.Run(stopCh)
+}
+
+func (i *informer) process(obj interface{}, isInInitialList bool) error {
+	i.handlerLock.RLock()
+	defer i.handlerLock.RUnlock()
+
+	for _, d := range obj.(cache.Deltas) {
+		switch d.Type {
+		case cache.Sync, cache.Replaced, cache.Added, cache.Updated:
+			old, exists, err := i.indexer.Get(d.Object)
+			if err != nil {
+				return err
+			}
+			if exists {
+				if err := i.indexer.Update(d.Object); err != nil {
+					return err
+				}
+				for _, handler := range i.handlers {
+					handler.OnUpdate(old, d.Object)
+				}
+				continue
+			}
+
+			if err := i.indexer.Add(d.Object); err != nil {
+				return err
+			}
+			for _, handler := range i.handlers {
+				handler.OnAdd(d.Object, isInInitialList)
+			}
+		case cache.Deleted:
+			if err := i.indexer.Delete(d.Object); err != nil {
+				return err
+			}
+			for _, handler := range i.handlers {
+				handler.OnDelete(d.Object)
+			}
+		}
+	}
+	return nil
+}
+
+// newListWatch lists and watches with ctx, which is cancelled when the informer is stopped.
+func newListWatch(ctx context.Context, client dynamic.NamespaceableResourceInterface, options metav1.ListOptions, relistPeriod time.Duration) *cache.ListWatch {
+	var unwatchable atomic.Bool
+	return &cache.ListWatch{
+		// the offset based pagination of clusterpedia is not compatible with the pager of the reflector,
+		// so the reflector's options are ignored and all of the pages are listed here.
+		ListFunc: func(_ metav1.ListOptions) (runtime.Object, error) {
+			list := &unstructured.UnstructuredList{}
+			p := pager.NewForOptions(func(ctx context.Context, opts metav1.ListOptions) (*pager.Page[unstructured.Unstructured], error) {
+				page, err := client.List(ctx, opts)
+				if err != nil {
+					return nil, err
+				}
+				list.SetResourceVersion(page.GetResourceVersion())
+				return &pager.Page[unstructured.Unstructured]{
+					Items:              page.Items,
+					Continue:           page.GetContinue(),
+					RemainingItemCount: page.GetRemainingItemCount(),
+				}, nil
+			}, options, listPageSize)
+
+			// the items listed before the pager restarts from an expired continue token are dropped
+			p.OnRestart(func() error {
+				list.Items = nil
+				return nil
+			})
+			if err := p.EachItem(ctx, func(item unstructured.Unstructured) error {
+				list.Items = append(list.Items, item)
+				return nil
+			}); err != nil {
+				return nil, err
+			}
+			return list, nil
+		},
+		WatchFunc: func(watchOptions metav1.ListOptions) (watch.Interface, error) {
+			if !unwatchable.Load() {
+				opts := options
+				opts.Continue, opts.Limit = "", 0
+				opts.ResourceVersion = watchOptions.ResourceVersion
+				opts.TimeoutSeconds = watchOptions.TimeoutSeconds
+				opts.AllowWatchBookmarks = watchOptions.AllowWatchBookmarks
+
+				w, err := client.Watch(ctx, opts)
+				if err == nil || !isWatchUnsupported(err) {
+					return w, err
+				}
+				unwatchable.Store(true)
+			}
+			return newRelistWatch(relistPeriod), nil
+		},
+	}
+}
+
+// isWatchUnsupported returns whether err means the server never supports the watch of the resource,
+// the other errors, e.g. the bad request of an expired resource version, are returned to the reflector to retry.
+func isWatchUnsupported(err error) bool {
+	return apierrors.IsMethodNotSupported(err) || apierrors.IsNotFound(err)
+}
+
+// relistWatch is used when the server doesn't support watch,
+// it expires after the relist period to make the reflector re-list.
+type relistWatch struct {
+	result chan watch.Event
+	stop   chan struct{}
+	once   sync.Once
+}
+
+func newRelistWatch(period time.Duration) watch.Interface {
+	w := &relistWatch{
+		result: make(chan watch.Event),
+		stop:   make(chan struct{}),
+	}
+	go func() {
+		defer close(w.result)
+
+		timer := time.NewTimer(period)
+		defer timer.Stop()
+		select {
+		case <-w.stop:
+			return
+		case <-timer.C:
+		}
+
+		select {
+		case <-w.stop:
+		case w.result <- watch.Event{Type: watch.Error, Object: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusGone,
+			Reason:  metav1.StatusReasonExpired,
+			Message: "relist period expired",
+		}}:
+		}
+	}()
+	return w
+}
+
+func (w *relistWatch) Stop() {
+	w.once.Do(func() { close(w.stop) })
+}
+
+func (w *relistWatch) ResultChan() <-chan watch.Event {
+	return w.result
+}
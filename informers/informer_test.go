@@ -0,0 +1,259 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/clusterpedia-io/client-go/constants"
+	"github.com/clusterpedia-io/client-go/dynamic"
+	"github.com/clusterpedia-io/client-go/tools/builder"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+const podTemplate = `{"apiVersion":"v1","kind":"Pod","metadata":{"name":%q,"namespace":%q,"labels":{"app":%q},"annotations":{"shadow.clusterpedia.io/cluster-name":%q}}}`
+
+func TestInformerRelist(t *testing.T) {
+	var lists int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != constants.ClusterPediaAPIPath+"/api/v1/pods" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("watch") == "true" {
+			http.Error(w, "watch is not supported", http.StatusMethodNotAllowed)
+			return
+		}
+		if !strings.Contains(r.URL.Query().Get("labelSelector"), "search.clusterpedia.io/clusters in (cluster-1,cluster-2)") {
+			http.Error(w, "unexpected label selector", http.StatusBadRequest)
+			return
+		}
+
+		pods := []string{
+			fmt.Sprintf(podTemplate, "nginx", "default", "nginx", "cluster-1"),
+			fmt.Sprintf(podTemplate, "nginx", "default", "nginx", "cluster-2"),
+		}
+		if atomic.AddInt32(&lists, 1) == 1 {
+			pods = append(pods, fmt.Sprintf(podTemplate, "coredns", "kube-system", "coredns", "cluster-1"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"apiVersion":"v1","kind":"PodList","metadata":{"resourceVersion":"1"},"items":[%s]}`, strings.Join(pods, ","))
+	}))
+	defer server.Close()
+
+	client, err := dynamic.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	factory := NewFilteredSharedInformerFactory(client, 100*time.Millisecond, builder.ListOptionsBuilder().Clusters("cluster-1", "cluster-2"))
+	pods := factory.ForResource(schema.GroupVersionResource{Version: "v1", Resource: "pods"})
+
+	var deleted int32
+	pods.Informer().AddEventHandler(cacheHandler(func(obj interface{}) {
+		atomic.AddInt32(&deleted, 1)
+	}))
+
+	stopCh := make(chan struct{})
+	defer factory.Shutdown()
+	defer close(stopCh)
+
+	factory.Start(stopCh)
+	for gvr, synced := range factory.WaitForCacheSync(stopCh) {
+		if !synced {
+			t.Fatalf("Informer of %s is not synced", gvr)
+		}
+	}
+
+	lister := pods.Lister()
+	for _, cluster := range []string{"cluster-1", "cluster-2"} {
+		obj, err := lister.Cluster(cluster).Namespace("default").Get("nginx")
+		if err != nil {
+			t.Fatalf("Unexpect error: %v", err)
+		}
+		if c := obj.(*unstructured.Unstructured).GetAnnotations()[constants.ShadowAnnotationClusterName]; c != cluster {
+			t.Errorf("Unexpect cluster: %s, expect: %s", c, cluster)
+		}
+	}
+	if objs, _ := lister.ByNamespace("default", labels.SelectorFromSet(labels.Set{"app": "nginx"})); len(objs) != 2 {
+		t.Errorf("Unexpect objects in default namespace: %d, expect: 2", len(objs))
+	}
+	if _, err := lister.Cluster("cluster-2").Namespace("kube-system").Get("coredns"); !errors.IsNotFound(err) {
+		t.Errorf("Expect not found error, got: %v", err)
+	}
+
+	// coredns is removed by the relist, since the server doesn't support watch
+	if err := wait.PollUntilContextTimeout(context.TODO(), 50*time.Millisecond, 10*time.Second, true, func(context.Context) (bool, error) {
+		objs, err := lister.Cluster("cluster-1").List(labels.Everything())
+		return len(objs) == 1 && atomic.LoadInt32(&deleted) == 1, err
+	}); err != nil {
+		t.Errorf("Informer didn't relist: %v", err)
+	}
+}
+
+type cacheHandler func(obj interface{})
+
+func (h cacheHandler) OnAdd(obj interface{}, isInInitialList bool) {}
+func (h cacheHandler) OnUpdate(oldObj, newObj interface{})         {}
+func (h cacheHandler) OnDelete(obj interface{})                    { h(obj) }
+
+func TestInformerWatch(t *testing.T) {
+	var watches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != constants.ClusterPediaAPIPath+"/api/v1/pods" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("watch") != "true" {
+			fmt.Fprintf(w, `{"apiVersion":"v1","kind":"PodList","metadata":{"resourceVersion":"1"},"items":[%s]}`,
+				fmt.Sprintf(podTemplate, "nginx", "default", "nginx", "cluster-1"))
+			return
+		}
+
+		// the bad request doesn't make the informer fall back to relist
+		if atomic.AddInt32(&watches, 1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"BadRequest","code":400}`)
+			return
+		}
+		fmt.Fprintf(w, `{"type":"ADDED","object":%s}`+"\n", fmt.Sprintf(podTemplate, "coredns", "kube-system", "coredns", "cluster-1"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client, err := dynamic.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	factory := NewSharedInformerFactory(client, 0)
+	pods := factory.ForResource(schema.GroupVersionResource{Version: "v1", Resource: "pods"})
+	pods.Informer()
+
+	stopCh := make(chan struct{})
+	defer factory.Shutdown()
+	defer close(stopCh)
+
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	if err := wait.PollUntilContextTimeout(context.TODO(), 50*time.Millisecond, 10*time.Second, true, func(context.Context) (bool, error) {
+		_, err := pods.Lister().Cluster("cluster-1").Namespace("kube-system").Get("coredns")
+		return err == nil, nil
+	}); err != nil {
+		t.Errorf("Informer didn't watch: %v", err)
+	}
+}
+
+func TestInformerStop(t *testing.T) {
+	listing, cancelled := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(listing)
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	defer server.Close()
+
+	client, err := dynamic.NewForConfig(&rest.Config{Host: server.URL, Timeout: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	informer := NewInformer(client, schema.GroupVersionResource{Version: "v1", Resource: "pods"}, 0, metav1.ListOptions{})
+	stopCh := make(chan struct{})
+	go informer.Run(stopCh)
+
+	<-listing
+	close(stopCh)
+	select {
+	case <-cancelled:
+	case <-time.After(10 * time.Second):
+		t.Errorf("Expect the list is cancelled when the informer is stopped")
+	}
+}
+
+func TestInformerListExpired(t *testing.T) {
+	var expired atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("watch") == "true" {
+			http.Error(w, "watch is not supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// the continue token of the second page is expired once, and nginx-0 is deleted meanwhile
+		offset, _ := strconv.Atoi(query.Get("continue"))
+		if offset > 0 && expired.CompareAndSwap(false, true) {
+			w.WriteHeader(http.StatusGone)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"Expired","code":410}`)
+			return
+		}
+		start, end := 0, listPageSize+100
+		if expired.Load() {
+			start++
+		}
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		var pods []string
+		for i := start + offset; i < start+offset+limit && i < end; i++ {
+			pods = append(pods, fmt.Sprintf(podTemplate, "nginx-"+strconv.Itoa(i), "default", "nginx", "cluster-1"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"apiVersion":"v1","kind":"PodList","metadata":{"resourceVersion":"1"},"items":[%s]}`, strings.Join(pods, ","))
+	}))
+	defer server.Close()
+
+	client, err := dynamic.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	informer := NewInformer(client, schema.GroupVersionResource{Version: "v1", Resource: "pods"}, 0, metav1.ListOptions{})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, informer.HasSynced) {
+		t.Fatal("Informer is not synced")
+	}
+
+	if !expired.Load() {
+		t.Errorf("Expect the continue token is expired")
+	}
+	if n := len(informer.GetIndexer().List()); n != listPageSize+99 {
+		t.Errorf("Unexpect objects: %d, expect: %d", n, listPageSize+99)
+	}
+	if _, ok, _ := informer.GetIndexer().GetByKey(Key("cluster-1", "default", "nginx-0")); ok {
+		t.Errorf("Expect the objects listed before the restart are dropped")
+	}
+}
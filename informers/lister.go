@@ -0,0 +1,143 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Lister lists the objects of all clusters from an informer's indexer.
+type Lister interface {
+	// List lists the objects of all clusters.
+	List(selector labels.Selector) ([]runtime.Object, error)
+	// ByNamespace lists the objects of the namespace in all clusters.
+	ByNamespace(namespace string, selector labels.Selector) ([]runtime.Object, error)
+	// Cluster returns a lister of the cluster.
+	Cluster(cluster string) ClusterLister
+}
+
+// ClusterLister lists the objects of a cluster.
+type ClusterLister interface {
+	List(selector labels.Selector) ([]runtime.Object, error)
+	// Get gets a cluster scoped object.
+	Get(name string) (runtime.Object, error)
+	Namespace(namespace string) NamespaceLister
+}
+
+// NamespaceLister lists the objects of a namespace in a cluster.
+type NamespaceLister interface {
+	List(selector labels.Selector) ([]runtime.Object, error)
+	Get(name string) (runtime.Object, error)
+}
+
+// NewLister returns a Lister of the indexer created by an Informer.
+func NewLister(indexer cache.Indexer, gvr schema.GroupVersionResource) Lister {
+	return &lister{indexer: indexer, resource: gvr.GroupResource()}
+}
+
+type lister struct {
+	indexer  cache.Indexer
+	resource schema.GroupResource
+}
+
+func (l *lister) List(selector labels.Selector) ([]runtime.Object, error) {
+	return filter(l.indexer.List(), selector)
+}
+
+func (l *lister) ByNamespace(namespace string, selector labels.Selector) ([]runtime.Object, error) {
+	objs, err := l.indexer.ByIndex(cache.NamespaceIndex, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return filter(objs, selector)
+}
+
+func (l *lister) Cluster(cluster string) ClusterLister {
+	return &clusterLister{lister: l, cluster: cluster}
+}
+
+type clusterLister struct {
+	*lister
+	cluster string
+}
+
+func (l *clusterLister) List(selector labels.Selector) ([]runtime.Object, error) {
+	objs, err := l.indexer.ByIndex(ClusterIndex, l.cluster)
+	if err != nil {
+		return nil, err
+	}
+	return filter(objs, selector)
+}
+
+func (l *clusterLister) Get(name string) (runtime.Object, error) {
+	return l.get(Key(l.cluster, "", name), name)
+}
+
+func (l *clusterLister) Namespace(namespace string) NamespaceLister {
+	return &namespaceLister{clusterLister: l, namespace: namespace}
+}
+
+type namespaceLister struct {
+	*clusterLister
+	namespace string
+}
+
+func (l *namespaceLister) List(selector labels.Selector) ([]runtime.Object, error) {
+	objs, err := l.indexer.ByIndex(ClusterNamespaceIndex, l.cluster+"/"+l.namespace)
+	if err != nil {
+		return nil, err
+	}
+	return filter(objs, selector)
+}
+
+func (l *namespaceLister) Get(name string) (runtime.Object, error) {
+	return l.get(Key(l.cluster, l.namespace, name), name)
+}
+
+func (l *lister) get(key, name string) (runtime.Object, error) {
+	obj, exists, err := l.indexer.GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(l.resource, name)
+	}
+	return obj.(runtime.Object), nil
+}
+
+func filter(objs []interface{}, selector labels.Selector) ([]runtime.Object, error) {
+	if selector == nil {
+		selector = labels.Everything()
+	}
+
+	ret := make([]runtime.Object, 0, len(objs))
+	for _, obj := range objs {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		if selector.Matches(labels.Set(accessor.GetLabels())) {
+			ret = append(ret, obj.(runtime.Object))
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics exports the prometheus metrics of the requests sent to clusterpedia,
+// the metrics are collected by wrapping the transport of the clients.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/clusterpedia-io/client-go/client"
+	"github.com/clusterpedia-io/client-go/constants"
+	"github.com/clusterpedia-io/client-go/pkg/requestinfo"
+	"github.com/clusterpedia-io/client-go/retry"
+)
+
+const namespace = "clusterpedia_client"
+
+var (
+	queryLabels = []string{"verb", "resource", "clusters", "fuzzy", "orderby", "remaining_count"}
+
+	// ItemsBuckets are the buckets of the number of the items of the list responses.
+	ItemsBuckets = prometheus.ExponentialBuckets(1, 4, 8)
+)
+
+// Metrics collects the metrics of the requests sent to clusterpedia.
+//
+// The labels of the metrics are bounded:
+//   - resource is the group/version/resource of the resources API, e.g. apps/v1/deployments,
+//     or collectionresources/<name> of the collection resources API, the object names are never used.
+//   - clusters is the bucket of the number of the clusters in the query: all, 1, 2-5 or 6+.
+//   - fuzzy, orderby and remaining_count are whether the search labels are used.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	items    *prometheus.HistogramVec
+	retries  *prometheus.CounterVec
+}
+
+// New creates the metrics and registers them into reg, e.g. the registry of controller-runtime.
+// If the metrics have been registered into reg, the registered metrics are reused.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Number of the requests sent to clusterpedia, code is empty if the request failed without a response.",
+		}, append(queryLabels, "code")),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "request_errors_total",
+			Help:      "Number of the failed requests sent to clusterpedia, reason is transport, body, 4xx or 5xx.",
+		}, []string{"verb", "resource", "reason"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the requests sent to clusterpedia, including reading the response body.",
+			Buckets:   prometheus.DefBuckets,
+		}, queryLabels),
+		items: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "response_items",
+			Help:      "Number of the items of the list responses and the fetched collection resources of clusterpedia.",
+			Buckets:   ItemsBuckets,
+		}, []string{"resource"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "request_retries_total",
+			Help:      "Number of the requests retried by the retry policy of the clients, they are also counted by requests_total.",
+		}, []string{"verb", "resource"}),
+	}
+
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	var err error
+	if m.requests, err = register(reg, m.requests); err != nil {
+		return nil, err
+	}
+	if m.errors, err = register(reg, m.errors); err != nil {
+		return nil, err
+	}
+	if m.latency, err = register(reg, m.latency); err != nil {
+		return nil, err
+	}
+	if m.items, err = register(reg, m.items); err != nil {
+		return nil, err
+	}
+	if m.retries, err = register(reg, m.retries); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func register[T prometheus.Collector](reg prometheus.Registerer, collector T) (T, error) {
+	if err := reg.Register(collector); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if errors.As(err, &registered) {
+			if existing, ok := registered.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return collector, err
+	}
+	return collector, nil
+}
+
+// Option returns the client option which collects the metrics of the clients,
+// it can be passed to the constructors of client, dynamic, customclient and clusterpediaclient.
+func (m *Metrics) Option() client.Option {
+	return client.WithWrapTransport(m.WrapTransport)
+}
+
+// WrapTransport returns a round tripper which collects the metrics of the requests sent by rt,
+// it can be used as the rest.Config.WrapTransport.
+func (m *Metrics) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &roundTripper{delegate: rt, metrics: m}
+}
+
+type roundTripper struct {
+	delegate http.RoundTripper
+	metrics  *Metrics
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	info := requestinfo.New(req)
+	q := newQuery(info)
+	if retry.Attempt(req.Context()) > 0 {
+		rt.metrics.retries.WithLabelValues(q.verb, q.resource).Inc()
+	}
+	start := time.Now()
+
+	resp, err := rt.delegate.RoundTrip(req)
+	if err != nil {
+		rt.metrics.observe(q, 0, time.Since(start), "transport")
+		return resp, err
+	}
+
+	resp.Body = requestinfo.NewBody(info, resp, func(_ int64, items int, err error) {
+		var reason string
+		switch {
+		case resp.StatusCode >= 500:
+			reason = "5xx"
+		case resp.StatusCode >= 400:
+			reason = "4xx"
+		case err != nil:
+			reason = "body"
+		}
+		rt.metrics.observe(q, resp.StatusCode, time.Since(start), reason)
+		if items >= 0 {
+			rt.metrics.items.WithLabelValues(q.resource).Observe(float64(items))
+		}
+	})
+	return resp, nil
+}
+
+func (rt *roundTripper) WrappedRoundTripper() http.RoundTripper {
+	return rt.delegate
+}
+
+func (m *Metrics) observe(q query, code int, latency time.Duration, reason string) {
+	var status string
+	if code != 0 {
+		status = strconv.Itoa(code)
+	}
+	m.requests.WithLabelValues(q.verb, q.resource, q.clusters, q.fuzzy, q.orderBy, q.remainingCount, status).Inc()
+	m.latency.WithLabelValues(q.verb, q.resource, q.clusters, q.fuzzy, q.orderBy, q.remainingCount).Observe(latency.Seconds())
+	if reason != "" {
+		m.errors.WithLabelValues(q.verb, q.resource, reason).Inc()
+	}
+}
+
+// query is the label values of a request.
+type query struct {
+	verb           string
+	resource       string
+	clusters       string
+	fuzzy          string
+	orderBy        string
+	remainingCount string
+}
+
+func newQuery(info *requestinfo.Info) query {
+	q := query{verb: info.Verb, resource: info.Resource, clusters: "all", fuzzy: "false", orderBy: "false", remainingCount: "false"}
+	if _, ok := info.SearchLabels[constants.SearchLabelFuzzyName]; ok {
+		q.fuzzy = "true"
+	}
+	if _, ok := info.SearchLabels[constants.SearchLabelOrderBy]; ok {
+		q.orderBy = "true"
+	}
+	for _, v := range info.SearchLabels[constants.SearchLabelWithRemainingCount] {
+		if v == "true" {
+			q.remainingCount = "true"
+		}
+	}
+
+	switch clusters := len(info.Clusters()); {
+	case clusters == 0:
+	case clusters == 1:
+		q.clusters = "1"
+	case clusters <= 5:
+		q.clusters = "2-5"
+	default:
+		q.clusters = "6+"
+	}
+	return q
+}
@@ -0,0 +1,316 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+
+	"github.com/clusterpedia-io/client-go/client"
+	"github.com/clusterpedia-io/client-go/clusterpediaclient"
+	"github.com/clusterpedia-io/client-go/customclient"
+	"github.com/clusterpedia-io/client-go/dynamic"
+	"github.com/clusterpedia-io/client-go/pkg/requestinfo"
+	"github.com/clusterpedia-io/client-go/retry"
+	"github.com/clusterpedia-io/client-go/tools/builder"
+	"github.com/clusterpedia-io/client-go/tools/clusterpediatest"
+)
+
+func TestParseRequest(t *testing.T) {
+	const host = "https://10.6.0.1:6443"
+	testCase := []struct {
+		name   string
+		method string
+		url    string
+		expect query
+	}{
+		{
+			"list all clusters",
+			http.MethodGet,
+			"/apis/clusterpedia.io/v1beta1/resources/apis/apps/v1/deployments",
+			query{"list", "apps/v1/deployments", "all", "false", "false", "false"},
+		},
+		{
+			"get namespaced object of cluster path",
+			http.MethodGet,
+			"/apis/clusterpedia.io/v1beta1/resources/clusters/cluster-1/api/v1/namespaces/default/pods/nginx",
+			query{"get", "v1/pods", "1", "false", "false", "false"},
+		},
+		{
+			"get namespace",
+			http.MethodGet,
+			"/apis/clusterpedia.io/v1beta1/resources/api/v1/namespaces/default",
+			query{"get", "v1/namespaces", "all", "false", "false", "false"},
+		},
+		{
+			"search labels",
+			http.MethodGet,
+			"/apis/clusterpedia.io/v1beta1/resources/apis/apps/v1/namespaces/default/deployments?labelSelector=" +
+				"search.clusterpedia.io%2Fclusters+in+%28c1%2Cc2%2Cc3%29%2Cinternalstorage.clusterpedia.io%2Ffuzzy-name%3Dngin%2C" +
+				"search.clusterpedia.io%2Forderby%3Dname%2Csearch.clusterpedia.io%2Fwith-remaining-count%3Dtrue",
+			query{"list", "apps/v1/deployments", "2-5", "true", "true", "true"},
+		},
+		{
+			"many clusters",
+			http.MethodGet,
+			"/apis/clusterpedia.io/v1beta1/resources/apis/apps/v1/deployments?labelSelector=" +
+				"search.clusterpedia.io%2Fclusters+in+%28c1%2Cc2%2Cc3%2Cc4%2Cc5%2Cc6%29",
+			query{"list", "apps/v1/deployments", "6+", "false", "false", "false"},
+		},
+		{
+			"watch",
+			http.MethodGet,
+			"/apis/clusterpedia.io/v1beta1/resources/api/v1/pods?watch=true",
+			query{"watch", "v1/pods", "all", "false", "false", "false"},
+		},
+		{
+			"fetch collection resource",
+			http.MethodGet,
+			"/apis/clusterpedia.io/v1beta1/collectionresources/workloads?limit=10",
+			query{"get", "collectionresources/workloads", "all", "false", "false", "false"},
+		},
+		{
+			"list collection resources",
+			http.MethodGet,
+			"/apis/clusterpedia.io/v1beta1/collectionresources",
+			query{"list", "collectionresources", "all", "false", "false", "false"},
+		},
+		{
+			"discovery",
+			http.MethodGet,
+			"/apis/clusterpedia.io/v1beta1/resources/clusters/cluster-1/apis/apps/v1",
+			query{"list", "discovery", "1", "false", "false", "false"},
+		},
+		{
+			"other path",
+			http.MethodPost,
+			"/api/v1/namespaces/default/pods",
+			query{"post", "other", "all", "false", "false", "false"},
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, host+test.url, nil)
+			if q := newQuery(requestinfo.New(req)); q != test.expect {
+				t.Errorf("Unexpect query: %+v, expect: %+v", q, test.expect)
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	server := clusterpediatest.NewTestServer(t, "../tools/clusterpediatest/testdata/resources.yaml")
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	listOptions := builder.ListOptionsBuilder().Clusters("cluster-1").Options()
+
+	testCase := []struct {
+		name     string
+		resource string
+		request  func(opts ...client.Option) error
+	}{
+		{"kubernetes", "apps/v1/deployments", func(opts ...client.Option) error {
+			kubeClient, err := client.NewForConfig(server.RESTConfig(), opts...)
+			if err != nil {
+				return err
+			}
+			_, err = kubeClient.AppsV1().Deployments("").List(context.TODO(), listOptions)
+			return err
+		}},
+		{"dynamic", "apps/v1/deployments", func(opts ...client.Option) error {
+			dc, err := dynamic.NewForConfig(server.RESTConfig(), opts...)
+			if err != nil {
+				return err
+			}
+			_, err = dc.Resource(deployments).List(context.TODO(), listOptions)
+			return err
+		}},
+		{"customclient", "apps/v1/deployments", func(opts ...client.Option) error {
+			c, err := customclient.NewForConfig(server.RESTConfig(), opts...)
+			if err != nil {
+				return err
+			}
+			return c.Resource(deployments).List(context.TODO(), listOptions, nil, &appsv1.DeploymentList{})
+		}},
+		{"clusterpediaclient", "collectionresources/workloads", func(opts ...client.Option) error {
+			cc, err := clusterpediaclient.NewForConfig(server.RESTConfig(), opts...)
+			if err != nil {
+				return err
+			}
+			_, err = cc.PediaClusterV1beta1().CollectionResource().Fetch(context.TODO(), "workloads", listOptions, nil)
+			return err
+		}},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := New(prometheus.NewRegistry())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := test.request(m.Option()); err != nil {
+				t.Fatal(err)
+			}
+
+			if count := testutil.CollectAndCount(m.requests); count != 1 {
+				t.Fatalf("Unexpect series of requests: %d", count)
+			}
+			var verb string
+			for _, v := range []string{"list", "get"} {
+				if testutil.ToFloat64(m.requests.WithLabelValues(v, test.resource, "1", "false", "false", "false", "200")) == 1 {
+					verb = v
+				}
+			}
+			if verb == "" {
+				t.Fatalf("Unexpect requests:\n%s", collect(t, m.requests))
+			}
+			if count := testutil.CollectAndCount(m.latency); count != 1 {
+				t.Errorf("Unexpect series of latency: %d", count)
+			}
+			if count := testutil.CollectAndCount(m.errors); count != 0 {
+				t.Errorf("Unexpect errors:\n%s", collect(t, m.errors))
+			}
+			if count := testutil.CollectAndCount(m.items); count != 1 {
+				t.Errorf("Unexpect series of items: %d", count)
+			}
+			if verb == "list" {
+				expect := `
+# HELP clusterpedia_client_response_items Number of the items of the list responses and the fetched collection resources of clusterpedia.
+# TYPE clusterpedia_client_response_items histogram
+clusterpedia_client_response_items_bucket{resource="apps/v1/deployments",le="1"} 0
+clusterpedia_client_response_items_bucket{resource="apps/v1/deployments",le="4"} 1
+clusterpedia_client_response_items_bucket{resource="apps/v1/deployments",le="16"} 1
+clusterpedia_client_response_items_bucket{resource="apps/v1/deployments",le="64"} 1
+clusterpedia_client_response_items_bucket{resource="apps/v1/deployments",le="256"} 1
+clusterpedia_client_response_items_bucket{resource="apps/v1/deployments",le="1024"} 1
+clusterpedia_client_response_items_bucket{resource="apps/v1/deployments",le="4096"} 1
+clusterpedia_client_response_items_bucket{resource="apps/v1/deployments",le="16384"} 1
+clusterpedia_client_response_items_bucket{resource="apps/v1/deployments",le="+Inf"} 1
+clusterpedia_client_response_items_sum{resource="apps/v1/deployments"} 3
+clusterpedia_client_response_items_count{resource="apps/v1/deployments"} 1
+`
+				if err := testutil.CollectAndCompare(m.items, strings.NewReader(expect)); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	m, err := New(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc, err := dynamic.NewForConfig(&rest.Config{Host: server.URL}, m.Option())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	if _, err := dc.Resource(pods).Namespace("default").Get(context.TODO(), "nginx", metav1.GetOptions{}); err == nil {
+		t.Fatal("Expect error")
+	}
+	server.Close()
+	if _, err := dc.Resource(pods).Namespace("default").Get(context.TODO(), "nginx", metav1.GetOptions{}); err == nil {
+		t.Fatal("Expect error")
+	}
+
+	// the client retries the 503 response without Retry-After, so only the count of the 5xx is checked
+	if count := testutil.ToFloat64(m.errors.WithLabelValues("get", "v1/pods", "5xx")); count < 1 {
+		t.Errorf("Unexpect 5xx errors: %v", count)
+	}
+	if count := testutil.ToFloat64(m.errors.WithLabelValues("get", "v1/pods", "transport")); count < 1 {
+		t.Errorf("Unexpect transport errors: %v", count)
+	}
+	if count := testutil.ToFloat64(m.requests.WithLabelValues("get", "v1/pods", "all", "false", "false", "false", "")); count < 1 {
+		t.Errorf("Unexpect requests without response: %v", count)
+	}
+}
+
+func TestMetricsRetries(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests++; requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	m, err := New(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc, err := dynamic.NewForConfig(&rest.Config{Host: server.URL}, m.Option(), client.WithRetry(retry.Policy{InitialBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	_, _ = dc.Resource(pods).Namespace("default").Get(context.TODO(), "nginx", metav1.GetOptions{})
+
+	if count := testutil.ToFloat64(m.retries.WithLabelValues("get", "v1/pods")); count != 1 {
+		t.Errorf("Unexpect retries: %v", count)
+	}
+	if count := testutil.ToFloat64(m.requests.WithLabelValues("get", "v1/pods", "all", "false", "false", "false", "503")); count != 1 {
+		t.Errorf("Unexpect requests of the first attempt: %v", count)
+	}
+}
+
+func TestNewRegistered(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m1, err := New(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2, err := New(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m1.requests != m2.requests || m1.errors != m2.errors || m1.latency != m2.latency || m1.items != m2.items || m1.retries != m2.retries {
+		t.Errorf("Expect the registered metrics are reused")
+	}
+}
+
+func collect(t *testing.T, c prometheus.Collector) string {
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	for _, family := range families {
+		out.WriteString(family.String())
+		out.WriteString("\n")
+	}
+	return out.String()
+}
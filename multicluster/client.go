@@ -0,0 +1,231 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster
+
+import (
+	"context"
+	"sync"
+
+	clusterv1alpha2 "github.com/clusterpedia-io/api/cluster/v1alpha2"
+	"github.com/clusterpedia-io/client-go/client"
+	pediadynamic "github.com/clusterpedia-io/client-go/dynamic"
+	"github.com/clusterpedia-io/client-go/pkg/generated/clientset/versioned/scheme"
+	clusterv1alpha2client "github.com/clusterpedia-io/client-go/pkg/generated/clientset/versioned/typed/cluster/v1alpha2"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultWorkers is the number of clusters requested concurrently by default.
+const DefaultWorkers = 10
+
+// Item is an object listed from a cluster.
+type Item struct {
+	Cluster string
+	Object  *unstructured.Unstructured
+}
+
+// ListResult is the merged result of listing multiple clusters.
+type ListResult struct {
+	// Items are ordered by the clusters they come from.
+	Items []Item
+	// Errors are the errors of the clusters that failed, the items of these clusters are not included.
+	Errors map[string]error
+}
+
+// Err aggregates the errors of all failed clusters, it returns nil if all clusters succeed.
+func (r *ListResult) Err() error {
+	errs := make([]error, 0, len(r.Errors))
+	for cluster, err := range r.Errors {
+		errs = append(errs, &ClusterError{Cluster: cluster, Err: err})
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// ClusterError is the error of a single cluster.
+type ClusterError struct {
+	Cluster string
+	Err     error
+}
+
+func (e *ClusterError) Error() string {
+	return "cluster " + e.Cluster + ": " + e.Err.Error()
+}
+
+func (e *ClusterError) Unwrap() error {
+	return e.Err
+}
+
+// Client sends the same request to the path of every cluster,
+// unlike the aggregated search path, the result of each cluster is requested separately.
+//
+// The clients of the clusters are cached by a dynamic.ClusterSet, they share the http.Client
+// and the rate limiter of the Client, so the QPS is the QPS of the Client however many clusters are requested.
+type Client struct {
+	clusters      *pediadynamic.ClusterSet
+	pediaClusters clusterv1alpha2client.ClusterV1alpha2Interface
+	workers       int
+}
+
+// NewForConfig creates a Client, workers bounds the number of clusters
+// requested concurrently, a workers <= 0 uses DefaultWorkers.
+// The options are applied to the requests of all clusters, except the cluster option.
+func NewForConfig(cfg *rest.Config, workers int, opts ...client.Option) (*Client, error) {
+	options := client.NewOptions(opts...)
+	options.Cluster = ""
+
+	config, err := options.ConfigFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// the PediaClusters are listed with the rate limiter of the clusters
+	cfg = rest.CopyConfig(cfg)
+	cfg.RateLimiter = client.SharedRateLimiter(config)
+	clusters, err := pediadynamic.NewClusterSetForConfig(cfg, client.DefaultClusterCacheSize, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	options.HTTPClient = clusters.HTTPClient()
+	pediaClusters, err := newPediaClusterClient(cfg, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return &Client{
+		clusters:      clusters,
+		pediaClusters: pediaClusters,
+		workers:       workers,
+	}, nil
+}
+
+// newPediaClusterClient creates the client of the PediaClusters with the http.Client of the options,
+// the generated clientset can't be created with a http.Client, so the defaults of the generated client are set here.
+func newPediaClusterClient(cfg *rest.Config, options *client.Options) (clusterv1alpha2client.ClusterV1alpha2Interface, error) {
+	config := rest.CopyConfig(cfg)
+	options.ApplyTo(config)
+	config.GroupVersion = &clusterv1alpha2.SchemeGroupVersion
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+
+	restClient, err := rest.RESTClientForConfigAndClient(config, options.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+	return clusterv1alpha2client.New(restClient), nil
+}
+
+// ReadyClusters returns the names of the PediaClusters whose Ready condition is true.
+func (c *Client) ReadyClusters(ctx context.Context) ([]string, error) {
+	clusters, err := c.pediaClusters.PediaClusters().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(clusters.Items))
+	for _, cluster := range clusters.Items {
+		if meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1alpha2.ReadyCondition) {
+			names = append(names, cluster.Name)
+		}
+	}
+	return names, nil
+}
+
+// ClusterClient returns the dynamic client of the cluster.
+func (c *Client) ClusterClient(cluster string) (dynamic.Interface, error) {
+	return c.clusters.Cluster(cluster)
+}
+
+// List lists the resource in every cluster, namespace is optional.
+// The returned result contains the items of the succeeded clusters and the errors of the failed clusters.
+func (c *Client) List(ctx context.Context, clusters []string, gvr schema.GroupVersionResource, namespace string, opts metav1.ListOptions) *ListResult {
+	return c.do(ctx, clusters, func(ctx context.Context, dc dynamic.Interface) ([]unstructured.Unstructured, error) {
+		list, err := dc.Resource(gvr).Namespace(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return list.Items, nil
+	})
+}
+
+// ListReady lists the resource in all of the ready clusters.
+func (c *Client) ListReady(ctx context.Context, gvr schema.GroupVersionResource, namespace string, opts metav1.ListOptions) (*ListResult, error) {
+	clusters, err := c.ReadyClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.List(ctx, clusters, gvr, namespace, opts), nil
+}
+
+type clusterResult struct {
+	items []unstructured.Unstructured
+	err   error
+}
+
+func (c *Client) do(ctx context.Context, clusters []string, fn func(ctx context.Context, dc dynamic.Interface) ([]unstructured.Unstructured, error)) *ListResult {
+	results := make([]clusterResult, len(clusters))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers && i < len(clusters); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				if err := ctx.Err(); err != nil {
+					results[index].err = err
+					continue
+				}
+
+				dc, err := c.ClusterClient(clusters[index])
+				if err != nil {
+					results[index].err = err
+					continue
+				}
+				results[index].items, results[index].err = fn(ctx, dc)
+			}
+		}()
+	}
+	for i := range clusters {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	result := &ListResult{Errors: make(map[string]error)}
+	for i, r := range results {
+		if r.err != nil {
+			result.Errors[clusters[i]] = r.err
+			continue
+		}
+		for j := range r.items {
+			result.Items = append(result.Items, Item{Cluster: clusters[i], Object: &r.items[j]})
+		}
+	}
+	return result
+}
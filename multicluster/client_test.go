@@ -0,0 +1,168 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/clusterpedia-io/client-go/client"
+	"github.com/clusterpedia-io/client-go/constants"
+	"github.com/clusterpedia-io/client-go/observer"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+func TestList(t *testing.T) {
+	var inflight, maxInflight int32
+	prefix := constants.ClusterPediaAPIPath + constants.ClusterAPIPath
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInflight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInflight, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		if r.URL.Path == "/apis/cluster.clusterpedia.io/v1alpha2/pediaclusters" {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"apiVersion":"cluster.clusterpedia.io/v1alpha2","kind":"PediaClusterList","items":[
+				{"metadata":{"name":"cluster-1"},"status":{"conditions":[{"type":"Ready","status":"True","reason":"Ready","lastTransitionTime":"2023-10-26T00:00:00Z"}]}},
+				{"metadata":{"name":"cluster-2"},"status":{"conditions":[{"type":"Ready","status":"False","reason":"NotReady","lastTransitionTime":"2023-10-26T00:00:00Z"}]}},
+				{"metadata":{"name":"cluster-3"},"status":{"conditions":[{"type":"Ready","status":"True","reason":"Ready","lastTransitionTime":"2023-10-26T00:00:00Z"}]}}]}`)
+			return
+		}
+
+		cluster, path, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, prefix), "/")
+		if path != "api/v1/namespaces/default/pods" || cluster == "cluster-3" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"apiVersion":"v1","kind":"PodList","metadata":{},"items":[
+			{"apiVersion":"v1","kind":"Pod","metadata":{"name":"%s-a","namespace":"default"}},
+			{"apiVersion":"v1","kind":"Pod","metadata":{"name":"%s-b","namespace":"default"}}]}`, cluster, cluster)
+	}))
+	defer server.Close()
+
+	c, err := NewForConfig(&rest.Config{Host: server.URL}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clusters, err := c.ReadyClusters(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(clusters, []string{"cluster-1", "cluster-3"}) {
+		t.Errorf("Unexpect ready clusters: %v", clusters)
+	}
+
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	result := c.List(context.TODO(), []string{"cluster-1", "cluster-2", "cluster-3", "cluster-4"}, pods, "default", metav1.ListOptions{})
+
+	var got []string
+	for _, item := range result.Items {
+		got = append(got, item.Cluster+"/"+item.Object.GetName())
+	}
+	expect := []string{
+		"cluster-1/cluster-1-a", "cluster-1/cluster-1-b",
+		"cluster-2/cluster-2-a", "cluster-2/cluster-2-b",
+		"cluster-4/cluster-4-a", "cluster-4/cluster-4-b",
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("Unexpect items: %v, expect: %v", got, expect)
+	}
+	if len(result.Errors) != 1 || result.Errors["cluster-3"] == nil {
+		t.Errorf("Unexpect errors: %v", result.Errors)
+	}
+	if result.Err() == nil {
+		t.Error("Expect aggregated error, got nil")
+	}
+	if max := atomic.LoadInt32(&maxInflight); max > 2 {
+		t.Errorf("Unexpect concurrent requests: %d, expect at most 2", max)
+	}
+}
+
+type countingTransport struct {
+	requests int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.requests, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClientOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/apis/cluster.clusterpedia.io/v1alpha2/pediaclusters" {
+			fmt.Fprint(w, `{"apiVersion":"cluster.clusterpedia.io/v1alpha2","kind":"PediaClusterList","items":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"apiVersion":"v1","kind":"PodList","metadata":{},"items":[]}`)
+	}))
+	defer server.Close()
+
+	recorder := &observer.Recorder{}
+	rt := &countingTransport{}
+	c, err := NewForConfig(&rest.Config{Host: server.URL}, 2,
+		client.WithObserver(recorder), client.WithHTTPClient(&http.Client{Transport: rt}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.ReadyClusters(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	if err := c.List(context.TODO(), []string{"cluster-1", "cluster-2"}, pods, "", metav1.ListOptions{}).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if events := recorder.Events(); len(events) != 3 {
+		t.Errorf("Unexpect observed requests: %d, expect: 3", len(events))
+	}
+	if requests := atomic.LoadInt32(&rt.requests); requests != 3 {
+		t.Errorf("Unexpect requests of the http client: %d, expect: 3", requests)
+	}
+
+	// the clients of the clusters are cached, not created for every List
+	dc1, err := c.ClusterClient("cluster-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc2, err := c.ClusterClient("cluster-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dc1 != dc2 {
+		t.Errorf("Expect the client of the cluster is cached")
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package observer
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// Slog logs the requests by the logger at the debug level, the requests marked by WithDebug are logged
+// at the info level and the failed requests are logged at the error level.
+func Slog(logger *slog.Logger) Observer {
+	return ObserverFunc(func(ctx context.Context, event Event) {
+		level := slog.LevelDebug
+		switch {
+		case event.Err != nil:
+			level = slog.LevelError
+		case event.Debug:
+			level = slog.LevelInfo
+		}
+		logger.Log(ctx, level, "clusterpedia request",
+			slog.String("method", event.Method),
+			slog.String("url", event.URL),
+			slog.Any("searchLabels", event.SearchLabels),
+			slog.Int("status", event.StatusCode),
+			slog.Duration("latency", event.Latency),
+			slog.Int64("size", event.ResponseSize),
+			slog.Any("warnings", event.Warnings),
+			slog.Int("attempt", event.Attempt),
+			slog.Any("err", event.Err),
+		)
+	})
+}
+
+// Logr logs the requests by the logger at V(level), the requests marked by WithDebug are logged at V(0)
+// and the failed requests are logged as errors.
+// The klog logger can be used by Logr(klog.Background(), 4).
+func Logr(logger logr.Logger, level int) Observer {
+	return ObserverFunc(func(ctx context.Context, event Event) {
+		keysAndValues := []interface{}{
+			"method", event.Method,
+			"url", event.URL,
+			"searchLabels", event.SearchLabels,
+			"status", event.StatusCode,
+			"latency", event.Latency,
+			"size", event.ResponseSize,
+			"warnings", event.Warnings,
+			"attempt", event.Attempt,
+		}
+		if event.Err != nil {
+			logger.Error(event.Err, "clusterpedia request", keysAndValues...)
+			return
+		}
+		v := level
+		if event.Debug {
+			v = 0
+		}
+		logger.V(v).Info("clusterpedia request", keysAndValues...)
+	})
+}
+
+// Recorder records the events, it is used to check the requests in tests.
+type Recorder struct {
+	lock   sync.Mutex
+	events []Event
+}
+
+func (r *Recorder) Observe(ctx context.Context, event Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+// Events returns a copy of the recorded events.
+func (r *Recorder) Events() []Event {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]Event(nil), r.events...)
+}
+
+// Reset removes the recorded events.
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.events = nil
+}
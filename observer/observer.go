@@ -0,0 +1,210 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package observer observes the requests sent to clusterpedia by the clients of this module,
+// the observers are registered by client.WithObserver.
+package observer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+
+	"github.com/clusterpedia-io/client-go/retry"
+)
+
+// Event is a finished request, the credentials of the request are redacted.
+type Event struct {
+	Method string
+	// URL is the request URL, the password of the user info is redacted.
+	URL string
+	// Header is the request header, the credentials of Authorization and Proxy-Authorization are redacted.
+	Header http.Header
+
+	// SearchLabels are the clusterpedia search labels of the label selector,
+	// e.g. search.clusterpedia.io/clusters.
+	SearchLabels map[string][]string
+
+	// StatusCode is 0 if the request failed without a response.
+	StatusCode int
+	// Latency is the duration from sending the request to closing or reading the end of the response body.
+	Latency time.Duration
+	// ResponseSize is the number of bytes of the response body read by the client.
+	ResponseSize int64
+	// Warnings are the texts of the Warning headers of the response.
+	Warnings []string
+	// Err is the error of the round trip.
+	Err error
+	// Attempt is the attempt of the request retried by the retry policy of the clients, it is 0 for the first attempt.
+	Attempt int
+	// Debug is true for the requests sent with the context returned by WithDebug,
+	// e.g. the requests of the Debug clients of customclient and clusterpediaclient.
+	Debug bool
+}
+
+type debugKey struct{}
+
+// debugState records whether the requests sent with the context returned by WithDebug are observed.
+type debugState struct {
+	observed atomic.Bool
+}
+
+// WithDebug returns a context which marks the requests sent with it as Event.Debug.
+func WithDebug(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugKey{}, &debugState{})
+}
+
+// Observed returns whether the requests sent with ctx, which is returned by WithDebug, are seen by the observers,
+// e.g. the Debug clients log the requests by the default logger of slog if no observer is registered.
+func Observed(ctx context.Context) bool {
+	state, ok := ctx.Value(debugKey{}).(*debugState)
+	return ok && state.observed.Load()
+}
+
+func isDebug(ctx context.Context) bool {
+	_, ok := ctx.Value(debugKey{}).(*debugState)
+	return ok
+}
+
+// Observer observes the finished requests, Observe may be called concurrently.
+type Observer interface {
+	Observe(ctx context.Context, event Event)
+}
+
+// ObserverFunc is a function implementing Observer.
+type ObserverFunc func(ctx context.Context, event Event)
+
+func (f ObserverFunc) Observe(ctx context.Context, event Event) {
+	f(ctx, event)
+}
+
+// WrapTransport returns a round tripper which notifies the observers of the requests sent by rt.
+func WrapTransport(rt http.RoundTripper, observers ...Observer) http.RoundTripper {
+	if len(observers) == 0 {
+		return rt
+	}
+	return &roundTripper{delegate: rt, observers: observers}
+}
+
+type roundTripper struct {
+	delegate  http.RoundTripper
+	observers []Observer
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	event := newEvent(req)
+	ctx, start := req.Context(), time.Now()
+	if state, ok := ctx.Value(debugKey{}).(*debugState); ok {
+		state.observed.Store(true)
+	}
+
+	resp, err := rt.delegate.RoundTrip(req)
+	if err != nil {
+		event.Latency, event.Err = time.Since(start), err
+		rt.notify(ctx, event)
+		return resp, err
+	}
+
+	event.StatusCode = resp.StatusCode
+	if warnings, _ := utilnet.ParseWarningHeaders(resp.Header.Values("Warning")); len(warnings) > 0 {
+		for _, warning := range warnings {
+			event.Warnings = append(event.Warnings, warning.Text)
+		}
+	}
+	resp.Body = &observedBody{ReadCloser: resp.Body, done: func(size int64, err error) {
+		event.Latency, event.ResponseSize, event.Err = time.Since(start), size, err
+		rt.notify(ctx, event)
+	}}
+	return resp, nil
+}
+
+func (rt *roundTripper) notify(ctx context.Context, event Event) {
+	for _, observer := range rt.observers {
+		observer.Observe(ctx, event)
+	}
+}
+
+func (rt *roundTripper) WrappedRoundTripper() http.RoundTripper {
+	return rt.delegate
+}
+
+// observedBody calls done once when the body is read to the end or closed.
+type observedBody struct {
+	io.ReadCloser
+	size int64
+	once sync.Once
+	done func(size int64, err error)
+}
+
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	switch {
+	case err == io.EOF:
+		b.once.Do(func() { b.done(b.size, nil) })
+	case err != nil:
+		b.once.Do(func() { b.done(b.size, err) })
+	}
+	return n, err
+}
+
+func (b *observedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.size, nil) })
+	return err
+}
+
+func newEvent(req *http.Request) Event {
+	event := Event{Method: req.Method, URL: req.URL.Redacted(), Header: redactHeader(req.Header), Attempt: retry.Attempt(req.Context()), Debug: isDebug(req.Context())}
+
+	selector, err := labels.Parse(req.URL.Query().Get("labelSelector"))
+	if err != nil {
+		return event
+	}
+	requirements, _ := selector.Requirements()
+	for _, r := range requirements {
+		if strings.Contains(r.Key(), "clusterpedia.io/") {
+			if event.SearchLabels == nil {
+				event.SearchLabels = make(map[string][]string)
+			}
+			event.SearchLabels[r.Key()] = r.Values().List()
+		}
+	}
+	return event
+}
+
+func redactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, key := range []string{"Authorization", "Proxy-Authorization"} {
+		values := redacted.Values(key)
+		for i, value := range values {
+			scheme, _, found := strings.Cut(value, " ")
+			if found {
+				values[i] = scheme + " <redacted>"
+			} else {
+				values[i] = "<redacted>"
+			}
+		}
+	}
+	return redacted
+}
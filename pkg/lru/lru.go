@@ -0,0 +1,111 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lru provides a size bounded cache which evicts the least recently used entries.
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache is a LRU cache safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	lock    sync.Mutex
+	size    int
+	entries *list.List
+	items   map[K]*list.Element
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// New creates a cache holding at most size entries, a size <= 0 is treated as 1.
+func New[K comparable, V any](size int) *Cache[K, V] {
+	if size <= 0 {
+		size = 1
+	}
+	return &Cache[K, V]{
+		size:    size,
+		entries: list.New(),
+		items:   make(map[K]*list.Element),
+	}
+}
+
+// Get returns the value of the key and marks the key as recently used.
+func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.entries.MoveToFront(e)
+		return e.Value.(*entry[K, V]).value, true
+	}
+	return value, false
+}
+
+// Add adds the value of the key, the least recently used entry is evicted if the cache is full.
+func (c *Cache[K, V]) Add(key K, value V) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.entries.MoveToFront(e)
+		e.Value.(*entry[K, V]).value = value
+		return
+	}
+
+	c.items[key] = c.entries.PushFront(&entry[K, V]{key: key, value: value})
+	if c.entries.Len() > c.size {
+		oldest := c.entries.Back()
+		c.entries.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry[K, V]).key)
+	}
+}
+
+// Remove removes the key from the cache.
+func (c *Cache[K, V]) Remove(key K) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.entries.Remove(e)
+		delete(c.items, key)
+	}
+}
+
+// RemoveIf removes the key from the cache if its value matches, it returns whether the key is removed.
+func (c *Cache[K, V]) RemoveIf(key K, match func(value V) bool) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.items[key]; ok && match(e.Value.(*entry[K, V]).value) {
+		c.entries.Remove(e)
+		delete(c.items, key)
+		return true
+	}
+	return false
+}
+
+// Len returns the number of the cached entries.
+func (c *Cache[K, V]) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.entries.Len()
+}
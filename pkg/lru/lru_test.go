@@ -0,0 +1,56 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lru
+
+import (
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	c := New[string, int](2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Unexpect value of a: %d, %v", v, ok)
+	}
+
+	// b is the least recently used
+	c.Add("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Expect b is evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Unexpect value of a: %d, %v", v, ok)
+	}
+
+	c.Add("c", 4)
+	if v, ok := c.Get("c"); !ok || v != 4 {
+		t.Errorf("Unexpect value of c: %d, %v", v, ok)
+	}
+
+	c.Remove("a")
+	if _, ok := c.Get("a"); ok || c.Len() != 1 {
+		t.Errorf("Expect a is removed, len: %d", c.Len())
+	}
+
+	if c.RemoveIf("c", func(v int) bool { return v == 3 }) {
+		t.Errorf("Expect c is not removed by the stale value")
+	}
+	if !c.RemoveIf("c", func(v int) bool { return v == 4 }) || c.Len() != 0 {
+		t.Errorf("Expect c is removed, len: %d", c.Len())
+	}
+}
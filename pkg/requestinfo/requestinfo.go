@@ -0,0 +1,297 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package requestinfo parses the requests sent to clusterpedia for the transport wrappers,
+// e.g. the metrics and the tracing.
+package requestinfo
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/clusterpedia-io/client-go/constants"
+)
+
+const (
+	// ResourceDiscovery is the resource of the discovery requests.
+	ResourceDiscovery = "discovery"
+	// ResourceOther is the resource of the requests which are not sent to the clusterpedia APIs.
+	ResourceOther = "other"
+
+	collectionResources = "collectionresources"
+)
+
+// Info is the information of a request sent to clusterpedia.
+type Info struct {
+	// Verb is get, list or watch for the GET requests, otherwise the lower case method.
+	Verb string
+	// Resource is the group/version/resource of the resources API, e.g. apps/v1/deployments,
+	// collectionresources/<name> of the collection resources API,
+	// ResourceDiscovery or ResourceOther.
+	Resource string
+	// Collection is the name of the fetched collection resource.
+	Collection string
+	// Cluster is the cluster of the cluster path, e.g. /clusters/<cluster>/apis/apps/v1/deployments.
+	Cluster   string
+	Namespace string
+	Name      string
+
+	// SearchLabels are the clusterpedia search labels of the label selector.
+	SearchLabels map[string][]string
+	Limit        int64
+	Continue     string
+}
+
+// Clusters returns the clusters of the cluster path or the search label.
+func (info *Info) Clusters() []string {
+	if info.Cluster != "" {
+		return []string{info.Cluster}
+	}
+	return info.SearchLabels[constants.SearchLabelClusters]
+}
+
+// Namespaces returns the namespaces of the path or the search label.
+func (info *Info) Namespaces() []string {
+	if info.Namespace != "" {
+		return []string{info.Namespace}
+	}
+	return info.SearchLabels[constants.SearchLabelNamespaces]
+}
+
+// New parses the request.
+func New(req *http.Request) *Info {
+	values := req.URL.Query()
+	info := &Info{Continue: values.Get("continue")}
+	info.Limit, _ = strconv.ParseInt(values.Get("limit"), 10, 64)
+	info.parsePath(req.URL.Path)
+
+	switch {
+	case req.Method != http.MethodGet:
+		info.Verb = strings.ToLower(req.Method)
+	case values.Get("watch") == "true" || values.Get("watch") == "1":
+		info.Verb = "watch"
+	case info.Name != "" || info.Collection != "":
+		info.Verb = "get"
+	default:
+		info.Verb = "list"
+	}
+
+	if selector, err := labels.Parse(values.Get("labelSelector")); err == nil {
+		requirements, _ := selector.Requirements()
+		for _, r := range requirements {
+			if strings.Contains(r.Key(), "clusterpedia.io/") {
+				if info.SearchLabels == nil {
+					info.SearchLabels = make(map[string][]string)
+				}
+				info.SearchLabels[r.Key()] = r.Values().List()
+			}
+		}
+	}
+	return info
+}
+
+func (info *Info) parsePath(path string) {
+	info.Resource = ResourceOther
+	i := strings.Index(path, constants.ClusterPediaOriginAPIPath+"/")
+	if i < 0 {
+		return
+	}
+	segments := strings.Split(strings.Trim(path[i+len(constants.ClusterPediaOriginAPIPath):], "/"), "/")
+
+	switch segments[0] {
+	case collectionResources:
+		info.Resource = collectionResources
+		if len(segments) > 1 {
+			info.Collection = segments[1]
+			info.Resource += "/" + info.Collection
+		}
+		return
+	case "resources":
+		segments = segments[1:]
+	default:
+		return
+	}
+
+	if len(segments) >= 2 && segments[0] == "clusters" {
+		info.Cluster, segments = segments[1], segments[2:]
+	}
+
+	info.Resource = ResourceDiscovery
+	var gv string
+	switch {
+	case len(segments) >= 2 && segments[0] == "api":
+		gv, segments = segments[1], segments[2:]
+	case len(segments) >= 3 && segments[0] == "apis":
+		gv, segments = segments[1]+"/"+segments[2], segments[3:]
+	default:
+		return
+	}
+	if len(segments) >= 3 && segments[0] == "namespaces" {
+		info.Namespace, segments = segments[1], segments[2:]
+	}
+	if len(segments) == 0 || segments[0] == "" {
+		return
+	}
+	info.Resource = gv + "/" + segments[0]
+	if len(segments) > 1 {
+		info.Name = segments[1]
+	}
+}
+
+// Body calls done once when the body is read to the end or closed.
+//
+// If the items are counted, the items of the list response are counted while the body is read,
+// the body isn't buffered, items is -1 if the items are not counted or the body is closed before the end.
+type Body struct {
+	io.ReadCloser
+	size int64
+	// items counts the items, it is nil if the items are not counted.
+	items *itemCounter
+	once  sync.Once
+	done  func(size int64, items int, err error)
+}
+
+// NewBody wraps the response body, the items are counted if the response is
+// a json list response or a fetched collection resource.
+func NewBody(info *Info, resp *http.Response, done func(size int64, items int, err error)) *Body {
+	body := &Body{ReadCloser: resp.Body, done: done}
+	if (info.Verb == "list" || info.Collection != "") && resp.StatusCode == http.StatusOK && isJSON(resp.Header.Get("Content-Type")) {
+		body.items = &itemCounter{}
+	}
+	return body
+}
+
+func (b *Body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if b.items != nil {
+		b.items.write(p[:n])
+	}
+	switch {
+	case err == io.EOF:
+		b.once.Do(func() { b.done(b.size, b.items.count(), nil) })
+	case err != nil:
+		b.once.Do(func() { b.done(b.size, -1, err) })
+	}
+	return n, err
+}
+
+func (b *Body) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.size, -1, nil) })
+	return err
+}
+
+// itemCounter counts the elements of the top level items array of a json object,
+// the json is scanned byte by byte, so the memory doesn't grow with the body.
+type itemCounter struct {
+	depth            int
+	started          bool
+	inString, escape bool
+
+	// key is the top level key being read, it is only kept up to the length of "items"
+	key       []byte
+	readKey   bool
+	expectKey bool
+	isItems   bool
+
+	inItems bool
+	pending bool
+	items   int
+}
+
+func (c *itemCounter) write(p []byte) {
+	for _, b := range p {
+		if c.inString {
+			switch {
+			case c.escape:
+				c.escape = false
+			case b == '\\':
+				c.escape = true
+			case b == '"':
+				c.inString = false
+				if c.readKey {
+					c.readKey, c.isItems = false, string(c.key) == "items"
+				}
+				continue
+			}
+			if c.readKey && len(c.key) <= len("items") {
+				c.key = append(c.key, b)
+			}
+			continue
+		}
+
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		c.started = true
+		if c.inItems && c.depth == 2 && !c.pending && b != ',' && b != ']' {
+			c.items, c.pending = c.items+1, true
+		}
+
+		switch b {
+		case '"':
+			c.inString = true
+			if c.depth == 1 && c.expectKey {
+				c.readKey, c.key = true, c.key[:0]
+			}
+		case '{', '[':
+			if c.depth == 1 && b == '[' && c.isItems && !c.expectKey {
+				c.inItems = true
+			}
+			c.depth++
+			if c.depth == 1 && b == '{' {
+				c.expectKey = true
+			}
+		case '}', ']':
+			c.depth--
+			if c.depth == 1 {
+				c.inItems = false
+			}
+		case ',':
+			switch {
+			case c.depth == 1:
+				c.expectKey, c.isItems = true, false
+			case c.depth == 2:
+				c.pending = false
+			}
+		case ':':
+			if c.depth == 1 {
+				c.expectKey = false
+			}
+		}
+	}
+}
+
+// count returns the number of the items, or -1 if the items are not counted or the json is incomplete.
+func (c *itemCounter) count() int {
+	if c == nil || !c.started || c.depth != 0 || c.inString {
+		return -1
+	}
+	return c.items
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
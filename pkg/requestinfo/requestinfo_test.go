@@ -0,0 +1,128 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package requestinfo
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestNew(t *testing.T) {
+	const host = "https://10.6.0.1:6443/apis/clusterpedia.io/v1beta1"
+	testCase := []struct {
+		name   string
+		url    string
+		expect Info
+	}{
+		{
+			"search labels",
+			host + "/resources/apis/apps/v1/deployments?limit=10&continue=20&labelSelector=app%3Dnginx%2C" +
+				"search.clusterpedia.io%2Fclusters+in+%28c1%2Cc2%29%2Csearch.clusterpedia.io%2Fnamespaces%3Ddefault",
+			Info{
+				Verb: "list", Resource: "apps/v1/deployments", Limit: 10, Continue: "20",
+				SearchLabels: map[string][]string{
+					"search.clusterpedia.io/clusters":   {"c1", "c2"},
+					"search.clusterpedia.io/namespaces": {"default"},
+				},
+			},
+		},
+		{
+			"cluster path",
+			host + "/resources/clusters/cluster-1/api/v1/namespaces/default/pods/nginx/status",
+			Info{Verb: "get", Resource: "v1/pods", Cluster: "cluster-1", Namespace: "default", Name: "nginx"},
+		},
+		{
+			"collection resource",
+			host + "/collectionresources/workloads",
+			Info{Verb: "get", Resource: "collectionresources/workloads", Collection: "workloads"},
+		},
+		{
+			"discovery",
+			host + "/resources/apis",
+			Info{Verb: "list", Resource: ResourceDiscovery},
+		},
+		{
+			"other",
+			"https://10.6.0.1:6443/api/v1/pods",
+			Info{Verb: "list", Resource: ResourceOther},
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			info := New(httptest.NewRequest(http.MethodGet, test.url, nil))
+			if !reflect.DeepEqual(*info, test.expect) {
+				t.Errorf("Unexpect info: %+v, expect: %+v", *info, test.expect)
+			}
+		})
+	}
+}
+
+func TestBody(t *testing.T) {
+	testCase := []struct {
+		name        string
+		url         string
+		contentType string
+		body        string
+		expectItems int
+	}{
+		{"list", "/apis/clusterpedia.io/v1beta1/resources/api/v1/pods", "application/json", `{"items":[{},{}]}`, 2},
+		{"collection resource", "/apis/clusterpedia.io/v1beta1/collectionresources/workloads", "application/json", `{"items":[{}]}`, 1},
+		{"get", "/apis/clusterpedia.io/v1beta1/resources/api/v1/namespaces/default/pods/nginx", "application/json", `{}`, -1},
+		{"protobuf", "/apis/clusterpedia.io/v1beta1/resources/api/v1/pods", "application/vnd.kubernetes.protobuf", "k8s", -1},
+		{
+			"nested items",
+			"/apis/clusterpedia.io/v1beta1/resources/api/v1/pods", "application/json",
+			`{"kind":"PodList","metadata":{"items":[1,2,3],"continue":"]["},` +
+				"\n" + `"items":[ {"spec":{"items":[{}]}} , "a\"],[" ,[1,[2]], 3, null, true ],"x":{"items":[1]}}`,
+			6,
+		},
+		{"empty items", "/apis/clusterpedia.io/v1beta1/resources/api/v1/pods", "application/json", `{"items":[]}`, 0},
+		{"null items", "/apis/clusterpedia.io/v1beta1/resources/api/v1/pods", "application/json", `{"items":null}`, 0},
+		{"escaped key", "/apis/clusterpedia.io/v1beta1/resources/api/v1/pods", "application/json", `{"item\"s":[1],"itemsx":[1]}`, 0},
+		{"incomplete", "/apis/clusterpedia.io/v1beta1/resources/api/v1/pods", "application/json", `{"items":[{},{}`, -1},
+		{"empty", "/apis/clusterpedia.io/v1beta1/resources/api/v1/pods", "application/json", ``, -1},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			info := New(httptest.NewRequest(http.MethodGet, test.url, nil))
+			resp := &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{test.contentType}},
+				// the body is read byte by byte to count the items across the reads
+				Body: io.NopCloser(iotest.OneByteReader(strings.NewReader(test.body))),
+			}
+
+			var calls, items int
+			var size int64
+			body := NewBody(info, resp, func(s int64, i int, err error) {
+				calls, size, items = calls+1, s, i
+			})
+			_, _ = io.ReadAll(body)
+			_ = body.Close()
+			if calls != 1 || size != int64(len(test.body)) || items != test.expectItems {
+				t.Errorf("Unexpect calls: %d, size: %d, items: %d", calls, size, items)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package responsecache caches the responses of the identical queries sent to clusterpedia for a short time,
+// and merges the concurrent identical queries into one round trip.
+package responsecache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/clusterpedia-io/client-go/pkg/lru"
+)
+
+const (
+	DefaultSize = 1000
+	DefaultTTL  = 5 * time.Second
+)
+
+// Stats are the statistics of the cache.
+type Stats struct {
+	// Hits is the number of the requests served by the cached responses.
+	Hits int64
+	// Misses is the number of the requests sent to clusterpedia.
+	Misses int64
+	// Coalesced is the number of the requests which waited for the identical request in flight.
+	Coalesced int64
+	// Entries is the number of the cached responses.
+	Entries int
+}
+
+// Cache caches the successful responses of the GET requests except the watches,
+// the cached responses expire after the TTL and the least recently used responses are evicted.
+//
+// The requests are keyed by the normalized query, the search labels and the requirements of the label selector
+// are sorted, so are the query parameters. The Authorization and Impersonate-* headers of the requests
+// and the scope of the transport are hashed into the key.
+//
+// The client certificates are not in the requests, so the transports which authenticate with
+// the different client certificates must be wrapped by WrapTransportWithScope with the different scopes,
+// the client options scope the cache by the TLS identity of the rest.Config.
+// A cache shared by the transports wrapped by WrapTransport is only safe if they share the TLS identity.
+type Cache struct {
+	ttl     time.Duration
+	entries *lru.Cache[string, *entry]
+
+	lock  sync.Mutex
+	calls map[string]*call
+
+	hits, misses, coalesced atomic.Int64
+
+	// now is replaced in the tests.
+	now func() time.Time
+}
+
+type entry struct {
+	response *response
+	expires  time.Time
+}
+
+// response is a response read to the end.
+type response struct {
+	status     string
+	statusCode int
+	proto      string
+	header     http.Header
+	body       []byte
+}
+
+// call is a request in flight.
+type call struct {
+	done     chan struct{}
+	response *response
+	err      error
+}
+
+// New creates a cache holding at most size responses for ttl,
+// DefaultSize and DefaultTTL are used if they are <= 0.
+func New(size int, ttl time.Duration) *Cache {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Cache{
+		ttl:     ttl,
+		entries: lru.New[string, *entry](size),
+		calls:   make(map[string]*call),
+		now:     time.Now,
+	}
+}
+
+// Stats returns the statistics of the cache.
+func (c *Cache) Stats() Stats {
+	return Stats{Hits: c.hits.Load(), Misses: c.misses.Load(), Coalesced: c.coalesced.Load(), Entries: c.entries.Len()}
+}
+
+// WrapTransport returns a round tripper which serves the requests sent by rt from the cache,
+// it can be used as the rest.Config.WrapTransport, see WrapTransportWithScope for the client certificates.
+func (c *Cache) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &roundTripper{delegate: rt, cache: c}
+}
+
+// WrapTransportWithScope returns the wrapper like WrapTransport, the responses cached by the transports
+// of the different scopes are not shared, e.g. the scope is the identity of the client certificate.
+func (c *Cache) WrapTransportWithScope(scope string) func(rt http.RoundTripper) http.RoundTripper {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &roundTripper{delegate: rt, cache: c, scope: scope}
+	}
+}
+
+type roundTripper struct {
+	delegate http.RoundTripper
+	cache    *Cache
+	scope    string
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !cacheable(req) {
+		return rt.delegate.RoundTrip(req)
+	}
+	return rt.cache.roundTrip(rt.delegate, req, scopedKey(rt.scope, req))
+}
+
+func (rt *roundTripper) WrappedRoundTripper() http.RoundTripper {
+	return rt.delegate
+}
+
+func (c *Cache) roundTrip(delegate http.RoundTripper, req *http.Request, key string) (*http.Response, error) {
+	if e, ok := c.entries.Get(key); ok {
+		if c.now().Before(e.expires) {
+			c.hits.Add(1)
+			return e.response.toResponse(req), nil
+		}
+		// the entry may have been replaced by the request in flight
+		c.entries.RemoveIf(key, func(cached *entry) bool { return cached == e })
+	}
+
+	c.lock.Lock()
+	if inflight, ok := c.calls[key]; ok {
+		c.lock.Unlock()
+		c.coalesced.Add(1)
+		select {
+		case <-inflight.done:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		// the request is sent again if the request in flight is canceled by its own context
+		if inflight.err == nil || !isContextError(inflight.err) {
+			return inflight.toResponse(req)
+		}
+		return c.roundTrip(delegate, req, key)
+	}
+	inflight := &call{done: make(chan struct{})}
+	c.calls[key] = inflight
+	c.lock.Unlock()
+
+	c.misses.Add(1)
+	inflight.response, inflight.err = send(delegate, req)
+	if inflight.err == nil && inflight.response.statusCode == http.StatusOK {
+		c.entries.Add(key, &entry{response: inflight.response, expires: c.now().Add(c.ttl)})
+	}
+
+	c.lock.Lock()
+	delete(c.calls, key)
+	c.lock.Unlock()
+	close(inflight.done)
+	return inflight.toResponse(req)
+}
+
+func (inflight *call) toResponse(req *http.Request) (*http.Response, error) {
+	if inflight.err != nil {
+		return nil, inflight.err
+	}
+	return inflight.response.toResponse(req), nil
+}
+
+func send(delegate http.RoundTripper, req *http.Request) (*response, error) {
+	resp, err := delegate.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &response{
+		status:     resp.Status,
+		statusCode: resp.StatusCode,
+		proto:      resp.Proto,
+		header:     resp.Header,
+		body:       body,
+	}, nil
+}
+
+func (r *response) toResponse(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        r.status,
+		StatusCode:    r.statusCode,
+		Proto:         r.proto,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.body)),
+		ContentLength: int64(len(r.body)),
+		Request:       req,
+	}
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// cacheable returns whether the request is a GET request except the watch.
+func cacheable(req *http.Request) bool {
+	if req.Method != http.MethodGet || (req.Body != nil && req.Body != http.NoBody) {
+		return false
+	}
+	watch := req.URL.Query().Get("watch")
+	return watch != "true" && watch != "1"
+}
+
+// Key returns the normalized key of the request, the identical queries have the same key
+// regardless of the order of the search labels and the query parameters.
+func Key(req *http.Request) string {
+	return scopedKey("", req)
+}
+
+func scopedKey(scope string, req *http.Request) string {
+	query := req.URL.Query()
+	if selector, err := labels.Parse(query.Get("labelSelector")); err == nil && query.Has("labelSelector") {
+		query.Set("labelSelector", selector.String())
+	}
+	for _, values := range query {
+		sort.Strings(values)
+	}
+
+	var key strings.Builder
+	u := url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host, Path: req.URL.Path, RawQuery: query.Encode()}
+	key.WriteString(u.String())
+	if accept := req.Header.Values("Accept"); len(accept) > 0 {
+		key.WriteString(" Accept=" + strings.Join(accept, ","))
+	}
+
+	// the credentials are the Authorization, the Impersonate-User, Impersonate-Group, Impersonate-Uid
+	// and Impersonate-Extra-* headers, and the scope of the transport
+	headers := make([]string, 0, len(req.Header))
+	for header := range req.Header {
+		if header == "Authorization" || strings.HasPrefix(header, "Impersonate-") {
+			headers = append(headers, header)
+		}
+	}
+	sort.Strings(headers)
+
+	credentials := sha256.New()
+	credentials.Write([]byte("scope=" + scope + "\n"))
+	for _, header := range headers {
+		credentials.Write([]byte(header + "=" + strings.Join(req.Header.Values(header), ",") + "\n"))
+	}
+	key.WriteString(" " + hex.EncodeToString(credentials.Sum(nil)))
+	return key.String()
+}
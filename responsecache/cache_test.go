@@ -0,0 +1,286 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package responsecache
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingBackend counts the requests, and responds the request path and query,
+// the requests are blocked until release is closed if it is set.
+type countingBackend struct {
+	requests atomic.Int32
+	status   int
+	release  chan struct{}
+}
+
+func (b *countingBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.requests.Add(1)
+	if b.release != nil {
+		<-b.release
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if b.status != 0 {
+		w.WriteHeader(b.status)
+	}
+	_, _ = io.WriteString(w, r.URL.RequestURI())
+}
+
+func get(t *testing.T, client *http.Client, url string, header http.Header) string {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestKey(t *testing.T) {
+	const path = "https://10.6.0.1:6443/apis/clusterpedia.io/v1beta1/resources/apis/apps/v1/deployments"
+	testCase := []struct {
+		name      string
+		a, b      string
+		headerA   http.Header
+		headerB   http.Header
+		expectHit bool
+	}{
+		{
+			"sorted search labels",
+			path + "?labelSelector=search.clusterpedia.io%2Fnamespaces%3Ddefault%2Csearch.clusterpedia.io%2Fclusters+in+%28c2%2Cc1%29",
+			path + "?labelSelector=search.clusterpedia.io%2Fclusters+in+%28c1%2Cc2%29%2Csearch.clusterpedia.io%2Fnamespaces%3Ddefault",
+			nil, nil, true,
+		},
+		{
+			"sorted params",
+			path + "?limit=10&onlyMetadata=true&continue=20",
+			path + "?continue=20&limit=10&onlyMetadata=true",
+			nil, nil, true,
+		},
+		{
+			"different search labels",
+			path + "?labelSelector=search.clusterpedia.io%2Fclusters%3Dc1",
+			path + "?labelSelector=search.clusterpedia.io%2Fclusters%3Dc2",
+			nil, nil, false,
+		},
+		{
+			"different path",
+			path,
+			path + "/nginx",
+			nil, nil, false,
+		},
+		{
+			"different credentials",
+			path, path,
+			http.Header{"Authorization": {"Bearer user-1"}}, http.Header{"Authorization": {"Bearer user-2"}},
+			false,
+		},
+		{
+			"different impersonated extra",
+			path, path,
+			http.Header{"Impersonate-User": {"user"}, "Impersonate-Extra-Scopes": {"view"}},
+			http.Header{"Impersonate-User": {"user"}, "Impersonate-Extra-Scopes": {"edit"}},
+			false,
+		},
+		{
+			"different accept",
+			path, path,
+			http.Header{"Accept": {"application/json"}}, http.Header{"Accept": {"application/vnd.kubernetes.protobuf"}},
+			false,
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := httptest.NewRequest(http.MethodGet, test.a, nil), httptest.NewRequest(http.MethodGet, test.b, nil)
+			a.Header, b.Header = test.headerA, test.headerB
+			if hit := Key(a) == Key(b); hit != test.expectHit {
+				t.Errorf("Unexpect keys:\n%s\n%s", Key(a), Key(b))
+			}
+		})
+	}
+}
+
+func TestCacheScope(t *testing.T) {
+	backend := &countingBackend{}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	cache := New(0, 0)
+	user1 := &http.Client{Transport: cache.WrapTransportWithScope("user-1")(http.DefaultTransport)}
+	user2 := &http.Client{Transport: cache.WrapTransportWithScope("user-2")(http.DefaultTransport)}
+	get(t, user1, server.URL+"/pods", nil)
+	get(t, user2, server.URL+"/pods", nil)
+	get(t, user2, server.URL+"/pods", nil)
+	if requests := backend.requests.Load(); requests != 2 {
+		t.Errorf("Expect the responses are not shared between the scopes, requests: %d", requests)
+	}
+}
+
+func TestCache(t *testing.T) {
+	backend := &countingBackend{}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	cache := New(2, time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	client := &http.Client{Transport: cache.WrapTransport(http.DefaultTransport)}
+
+	if body := get(t, client, server.URL+"/pods?b=2&a=1", nil); body != "/pods?b=2&a=1" {
+		t.Errorf("Unexpect body: %s", body)
+	}
+	if body := get(t, client, server.URL+"/pods?a=1&b=2", nil); body != "/pods?b=2&a=1" {
+		t.Errorf("Expect the cached response, got: %s", body)
+	}
+	if requests := backend.requests.Load(); requests != 1 {
+		t.Errorf("Unexpect requests: %d", requests)
+	}
+
+	// watch is not cached
+	get(t, client, server.URL+"/pods?watch=true", nil)
+	get(t, client, server.URL+"/pods?watch=true", nil)
+	if requests := backend.requests.Load(); requests != 3 {
+		t.Errorf("Unexpect requests: %d", requests)
+	}
+
+	// expired
+	now = now.Add(time.Minute)
+	get(t, client, server.URL+"/pods?a=1&b=2", nil)
+	if requests := backend.requests.Load(); requests != 4 {
+		t.Errorf("Expect the expired response is requested again, requests: %d", requests)
+	}
+
+	// evicted
+	get(t, client, server.URL+"/deployments", nil)
+	get(t, client, server.URL+"/daemonsets", nil)
+	get(t, client, server.URL+"/pods?a=1&b=2", nil)
+	if requests := backend.requests.Load(); requests != 7 {
+		t.Errorf("Expect the least recently used response is evicted, requests: %d", requests)
+	}
+
+	stats := cache.Stats()
+	if stats != (Stats{Hits: 1, Misses: 5, Entries: 2}) {
+		t.Errorf("Unexpect stats: %+v", stats)
+	}
+}
+
+func TestCacheErrorResponse(t *testing.T) {
+	backend := &countingBackend{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	cache := New(0, 0)
+	client := &http.Client{Transport: cache.WrapTransport(http.DefaultTransport)}
+	get(t, client, server.URL+"/pods", nil)
+	get(t, client, server.URL+"/pods", nil)
+	if requests := backend.requests.Load(); requests != 2 {
+		t.Errorf("Expect the error responses are not cached, requests: %d", requests)
+	}
+}
+
+func TestCacheCoalesce(t *testing.T) {
+	backend := &countingBackend{release: make(chan struct{})}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	cache := New(0, 0)
+	client := &http.Client{Transport: cache.WrapTransport(http.DefaultTransport)}
+
+	const concurrency = 10
+	var wg sync.WaitGroup
+	bodies := make([]string, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i] = get(t, client, server.URL+"/pods?limit=1", nil)
+		}(i)
+	}
+
+	// release the request after the other requests wait for it
+	for cache.Stats().Coalesced != concurrency-1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(backend.release)
+	wg.Wait()
+
+	if requests := backend.requests.Load(); requests != 1 {
+		t.Errorf("Expect the identical requests are merged, requests: %d", requests)
+	}
+	for _, body := range bodies {
+		if body != "/pods?limit=1" {
+			t.Errorf("Unexpect body: %s", body)
+		}
+	}
+	if stats := cache.Stats(); stats.Misses != 1 || stats.Hits != 0 {
+		t.Errorf("Unexpect stats: %+v", stats)
+	}
+}
+
+func TestCacheCoalesceCanceled(t *testing.T) {
+	backend := &countingBackend{release: make(chan struct{})}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	cache := New(0, 0)
+	client := &http.Client{Transport: cache.WrapTransport(http.DefaultTransport)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error)
+	go func() {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/pods", nil)
+		_, err := client.Do(req)
+		canceled <- err
+	}()
+	for backend.requests.Load() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	waiting := make(chan string)
+	go func() { waiting <- get(t, client, server.URL+"/pods", nil) }()
+	for cache.Stats().Coalesced != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-canceled; err == nil {
+		t.Fatal("Expect the canceled error")
+	}
+	close(backend.release)
+	if body := <-waiting; body != "/pods" {
+		t.Errorf("Unexpect body: %s", body)
+	}
+	if requests := backend.requests.Load(); requests != 2 {
+		t.Errorf("Expect the waiting request is sent again, requests: %d", requests)
+	}
+}
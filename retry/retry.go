@@ -0,0 +1,216 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package retry retries the idempotent reads sent to clusterpedia which failed transiently,
+// e.g. the 429 and 503 responses and the reset connections while the storage of clusterpedia is under load.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	DefaultMaxRetries     = 3
+	DefaultInitialBackoff = 200 * time.Millisecond
+	DefaultMaxBackoff     = 5 * time.Second
+	DefaultJitter         = 0.5
+)
+
+// Policy is the retry policy of the requests.
+//
+// The waits between the attempts grow exponentially from InitialBackoff to MaxBackoff with the jitter,
+// the Retry-After of the 429 and 503 responses is honoured instead of the backoff, it is capped by MaxBackoff too.
+// The retries stop when the next attempt would start after the deadline of the request context.
+//
+// The Retry-After of the response returned after the retries is removed, so that the rest client
+// doesn't retry it again, but the rest client still retries the GET requests of the reset connections.
+type Policy struct {
+	// MaxRetries is the max number of the retries after the first attempt,
+	// DefaultMaxRetries is used if it is 0, and the requests are not retried if it is negative.
+	MaxRetries int
+	// InitialBackoff is the wait before the first retry, DefaultInitialBackoff is used if it is 0.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff and the Retry-After, DefaultMaxBackoff is used if it is 0.
+	MaxBackoff time.Duration
+	// Jitter adds a random duration up to Jitter*backoff to the backoff, DefaultJitter is used if it is 0.
+	Jitter float64
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxRetries == 0 {
+		p.MaxRetries = DefaultMaxRetries
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = DefaultInitialBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = DefaultMaxBackoff
+	}
+	if p.Jitter == 0 {
+		p.Jitter = DefaultJitter
+	}
+	return p
+}
+
+// Backoff returns the wait before the retry, retry starts from 1.
+func (p Policy) Backoff(retry int) time.Duration {
+	p = p.withDefaults()
+	backoff := p.InitialBackoff
+	for i := 1; i < retry && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return wait.Jitter(backoff, p.Jitter)
+}
+
+type attemptKey struct{}
+
+// Attempt returns the attempt of the request sent with ctx, it is 0 for the first attempt,
+// the observers and the transport wrappers inside the retry use it to tell the retries.
+func Attempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+// WrapTransport returns a round tripper which retries the idempotent reads sent by rt with the policy.
+func WrapTransport(rt http.RoundTripper, policy Policy) http.RoundTripper {
+	return &roundTripper{delegate: rt, policy: policy.withDefaults()}
+}
+
+type roundTripper struct {
+	delegate http.RoundTripper
+	policy   Policy
+
+	// sleep is replaced in the tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !idempotent(req) || rt.policy.MaxRetries < 0 {
+		return rt.delegate.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 {
+			attemptReq = req.WithContext(context.WithValue(ctx, attemptKey{}, attempt))
+		}
+		resp, err := rt.delegate.RoundTrip(attemptReq)
+		if !retriable(ctx, resp, err) {
+			return resp, err
+		}
+
+		wait := rt.policy.Backoff(attempt + 1)
+		if retryAfter, ok := retryAfter(resp); ok {
+			wait = retryAfter
+			if wait > rt.policy.MaxBackoff {
+				wait = rt.policy.MaxBackoff
+			}
+		}
+		if deadline, ok := ctx.Deadline(); attempt >= rt.policy.MaxRetries || (ok && time.Now().Add(wait).After(deadline)) {
+			if resp != nil {
+				// the rest client retries the responses with Retry-After again, the policy has given up
+				resp.Header.Del("Retry-After")
+			}
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+		}
+		if err := rt.wait(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (rt *roundTripper) wait(ctx context.Context, d time.Duration) error {
+	if rt.sleep != nil {
+		return rt.sleep(ctx, d)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (rt *roundTripper) WrappedRoundTripper() http.RoundTripper {
+	return rt.delegate
+}
+
+// idempotent returns whether the request is an idempotent read which can be retried,
+// the requests with a body are not retried because the body can't be sent again.
+func idempotent(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody
+}
+
+func retriable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		var netErr net.Error
+		return utilnet.IsConnectionReset(err) || utilnet.IsConnectionRefused(err) || utilnet.IsProbableEOF(err) ||
+			(errors.As(err, &netErr) && netErr.Timeout())
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the Retry-After of the 429 and 503 responses, in seconds or a http date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// attemptsRoundTripper records the attempts of the requests.
+type attemptsRoundTripper struct {
+	delegate http.RoundTripper
+	attempts []int
+}
+
+func (rt *attemptsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.attempts = append(rt.attempts, Attempt(req.Context()))
+	return rt.delegate.RoundTrip(req)
+}
+
+// flakyHandler fails the first failures requests by fail.
+func flakyHandler(failures int32, fail func(w http.ResponseWriter)) (http.Handler, *atomic.Int32) {
+	var requests atomic.Int32
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			fail(w)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}), &requests
+}
+
+func TestRetry(t *testing.T) {
+	unavailable := func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) }
+	testCase := []struct {
+		name   string
+		method string
+		fail   func(w http.ResponseWriter)
+		policy Policy
+
+		expectStatus   int
+		expectAttempts []int
+		expectWaits    []time.Duration
+	}{
+		{
+			"retry 503",
+			http.MethodGet, unavailable,
+			Policy{InitialBackoff: time.Second, Jitter: 0.001},
+			http.StatusOK, []int{0, 1, 2}, []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			"honour retry after",
+			http.MethodGet,
+			func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "7")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			Policy{MaxBackoff: 10 * time.Second},
+			http.StatusOK, []int{0, 1, 2}, []time.Duration{7 * time.Second, 7 * time.Second},
+		},
+		{
+			"retry after capped by max backoff",
+			http.MethodGet,
+			func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "60")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			Policy{},
+			http.StatusOK, []int{0, 1, 2}, []time.Duration{DefaultMaxBackoff, DefaultMaxBackoff},
+		},
+		{
+			"connection reset",
+			http.MethodGet,
+			func(w http.ResponseWriter) {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+			},
+			Policy{InitialBackoff: time.Second, Jitter: 0.001},
+			http.StatusOK, []int{0, 1, 2}, []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			"exhausted",
+			http.MethodGet,
+			func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			Policy{MaxRetries: 1},
+			http.StatusServiceUnavailable, []int{0, 1}, []time.Duration{time.Second},
+		},
+		{
+			"disabled",
+			http.MethodGet, unavailable,
+			Policy{MaxRetries: -1},
+			http.StatusServiceUnavailable, []int{0}, nil,
+		},
+		{
+			"not idempotent",
+			http.MethodPost, unavailable,
+			Policy{},
+			http.StatusServiceUnavailable, []int{0}, nil,
+		},
+		{
+			"not transient",
+			http.MethodGet,
+			func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+			Policy{},
+			http.StatusInternalServerError, []int{0}, nil,
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			handler, _ := flakyHandler(2, test.fail)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			attempts := &attemptsRoundTripper{delegate: &http.Transport{DisableKeepAlives: true}}
+			rt := WrapTransport(attempts, test.policy).(*roundTripper)
+			var waits []time.Duration
+			rt.sleep = func(ctx context.Context, d time.Duration) error {
+				waits = append(waits, d.Round(time.Second))
+				return nil
+			}
+
+			req, _ := http.NewRequest(test.method, server.URL, nil)
+			resp, err := (&http.Client{Transport: rt}).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != test.expectStatus {
+				t.Errorf("Unexpect status: %d, expect: %d", resp.StatusCode, test.expectStatus)
+			}
+			if resp.Header.Get("Retry-After") != "" {
+				t.Errorf("Expect the Retry-After is removed after the retries")
+			}
+			if !reflect.DeepEqual(attempts.attempts, test.expectAttempts) {
+				t.Errorf("Unexpect attempts: %v, expect: %v", attempts.attempts, test.expectAttempts)
+			}
+			if !reflect.DeepEqual(waits, test.expectWaits) {
+				t.Errorf("Unexpect waits: %v, expect: %v", waits, test.expectWaits)
+			}
+		})
+	}
+}
+
+func TestRetryDeadline(t *testing.T) {
+	handler, requests := flakyHandler(10, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := (&http.Client{Transport: WrapTransport(http.DefaultTransport, Policy{})}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || requests.Load() != 1 {
+		t.Errorf("Expect not to retry after the deadline, status: %d, requests: %d", resp.StatusCode, requests.Load())
+	}
+
+	handler, requests = flakyHandler(10, func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) })
+	server = httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel = context.WithCancel(context.Background())
+	rt := WrapTransport(http.DefaultTransport, Policy{InitialBackoff: time.Hour}).(*roundTripper)
+	rt.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if _, err := (&http.Client{Transport: rt}).Do(req); err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Errorf("Expect the context error, got: %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("Unexpect requests: %d", requests.Load())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second, Jitter: 0.5}
+	for retry, expect := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		for i := 0; i < 10; i++ {
+			if backoff := policy.Backoff(retry); backoff < expect || backoff > expect+expect/2 {
+				t.Errorf("Unexpect backoff of retry %d: %s, expect: %s with jitter", retry, backoff, expect)
+			}
+		}
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/clusterpedia-io/client-go/constants"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// FromListOptions restores a builder from the list options, e.g. the options received by a gateway,
+// so that more conditions can be added without manipulating the selector strings.
+//
+// The clusterpedia search labels of the label selector are restored by the builder methods,
+// and the other requirements are kept in the builder as the user's label selector.
+// Limit and Continue are restored as Limit and Offset, a continue token which isn't an offset
+// is kept as is.
+//
+// Clusters and Namespaces add to the restored search labels, use RestrictClusters and RestrictNamespaces
+// to enforce the scope of the caller, or SetClusters and SetNamespaces to replace them.
+func FromListOptions(options metav1.ListOptions) (ListOptionsInterface, error) {
+	opts := ListOptionsBuilder().(*listOptions)
+	opts.options = *options.DeepCopy()
+	opts.options.LabelSelector, opts.options.FieldSelector = "", ""
+
+	selector, err := labels.Parse(options.LabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector: %w", err)
+	}
+	requirements, _ := selector.Requirements()
+
+	var userRequirements []labels.Requirement
+	for _, r := range requirements {
+		if !isSearchLabel(r.Key()) {
+			userRequirements = append(userRequirements, r)
+			continue
+		}
+		if err := opts.restoreSearchLabel(r); err != nil {
+			return nil, err
+		}
+	}
+	if len(userRequirements) > 0 {
+		opts.Selector(labels.NewSelector().Add(userRequirements...))
+	}
+
+	fieldRequirements, err := parseFieldSelector(options.FieldSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field selector: %w", err)
+	}
+	for _, r := range fieldRequirements {
+		switch r.operator {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			opts.FieldSelector(r.field, r.values)
+		case selection.NotEquals, selection.NotIn:
+			opts.FieldNotIn(r.field, r.values)
+		case selection.Exists:
+			opts.FieldExists(r.field)
+		case selection.DoesNotExist:
+			opts.FieldNotExists(r.field)
+		default:
+			return nil, fmt.Errorf("invalid field selector: operator %q of %s is not supported", r.operator, r.field)
+		}
+	}
+	return opts, nil
+}
+
+// FromClientListOptions restores a builder from the controller-runtime list options like FromListOptions,
+// the label selector, field selector, limit and continue of options override the fields of options.Raw.
+// options.Namespace is the namespace of the request path, so it isn't added to the search labels.
+func FromClientListOptions(options *client.ListOptions) (ListOptionsInterface, error) {
+	if options == nil {
+		return ListOptionsBuilder(), nil
+	}
+
+	var raw metav1.ListOptions
+	if options.Raw != nil {
+		raw = *options.Raw
+	}
+	if options.LabelSelector != nil {
+		raw.LabelSelector = options.LabelSelector.String()
+	}
+	if options.FieldSelector != nil {
+		raw.FieldSelector = options.FieldSelector.String()
+	}
+	if !raw.Watch {
+		raw.Limit, raw.Continue = options.Limit, options.Continue
+	}
+	return FromListOptions(raw)
+}
+
+func (opts *listOptions) restoreSearchLabel(r labels.Requirement) error {
+	values, err := requirementValues(r)
+	if err != nil {
+		return err
+	}
+
+	single := func() (string, error) {
+		if len(values) != 1 {
+			return "", fmt.Errorf("%s only accepts a single value", r.Key())
+		}
+		return values[0], nil
+	}
+
+	switch r.Key() {
+	case constants.SearchLabelClusters:
+		opts.Clusters(values...)
+	case constants.SearchLabelNamespaces:
+		opts.Namespaces(values...)
+	case constants.SearchLabelNames:
+		opts.Names(values...)
+	case constants.SearchLabelFuzzyName:
+		opts.FuzzyNames(values...)
+	case constants.SearchLabelOrderBy:
+		for _, v := range values {
+			field, desc := strings.CutSuffix(v, constants.OrderByDesc)
+			opts.OrderBy(field, desc)
+		}
+	case constants.SearchLabelWithRemainingCount:
+		opts.labels[r.Key()] = values
+	case constants.SearchLabelWithContinue:
+		opts.labels[r.Key()] = values
+	case constants.SearchLabelOwnerUID, constants.SearchLabelOwnerName:
+		v, err := single()
+		if err != nil {
+			return err
+		}
+		if r.Key() == constants.SearchLabelOwnerUID {
+			opts.OwnerUID(v)
+		} else {
+			opts.OwnerName(v)
+		}
+	case constants.SearchLabelOwnerGR:
+		v, err := single()
+		if err != nil {
+			return err
+		}
+		opts.OwnerGroupResource(schema.ParseGroupResource(v))
+	case constants.SearchLabelOwnerSeniority:
+		v, err := single()
+		if err != nil {
+			return err
+		}
+		seniority, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", r.Key(), err)
+		}
+		opts.OwnerSeniority(seniority)
+	case constants.SearchLabelSince, constants.SearchLabelBefore:
+		v, err := single()
+		if err != nil {
+			return err
+		}
+		t, err := ParseTime(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", r.Key(), err)
+		}
+		if r.Key() == constants.SearchLabelSince {
+			opts.Since(t)
+		} else {
+			opts.Before(t)
+		}
+	case constants.SearchLabelLimit, constants.SearchLabelOffset:
+		v, err := single()
+		if err != nil {
+			return err
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", r.Key(), err)
+		}
+		// the fields of list options take precedence over the search labels
+		if r.Key() == constants.SearchLabelLimit && opts.options.Limit == 0 {
+			opts.Limit(n)
+		}
+		if r.Key() == constants.SearchLabelOffset && opts.options.Continue == "" {
+			opts.Offset(n)
+		}
+	default:
+		// keep the unknown search labels, clusterpedia reports the unsupported ones
+		opts.LabelSelector(r.Key(), values)
+	}
+	return nil
+}
+
+func requirementValues(r labels.Requirement) ([]string, error) {
+	switch r.Operator() {
+	case selection.Equals, selection.DoubleEquals, selection.In:
+		return r.Values().List(), nil
+	}
+	return nil, fmt.Errorf("operator %q of %s is not supported", r.Operator(), r.Key())
+}
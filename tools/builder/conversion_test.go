@@ -0,0 +1,263 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/clusterpedia-io/client-go/constants"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestFromListOptions(t *testing.T) {
+	date := time.Date(2023, 10, 26, 0, 0, 0, 0, time.UTC)
+	testCase := []struct {
+		name string
+		opts ListOptionsInterface
+	}{
+		{"empty", ListOptionsBuilder()},
+		{
+			"search labels",
+			ListOptionsBuilder().Clusters("cluster-1", "cluster-2").Namespaces("default").Names("nginx").
+				FuzzyNames("ngin").OrderBy("name").OrderBy("created_at", true).RemainingCount().WithContinue(),
+		},
+		{
+			"owner and time",
+			ListOptionsBuilder().Owner(OwnerQuery{Name: "nginx", GroupResource: deployments, Seniority: 1}).
+				Since(date).Before(date.Add(time.Hour)),
+		},
+		{
+			"user labels and fields",
+			ListOptionsBuilder().Clusters("cluster-1").
+				Selector(labels.SelectorFromSet(labels.Set{"app": "nginx"})).
+				LabelSelector("tier", []string{"frontend", "backend"}).
+				FieldSelector("status.phase", []string{"Running"}),
+		},
+		{
+			"label requirements",
+			ListOptionsBuilder().Namespaces("default").LabelNotIn("env", []string{"test", "dev"}).
+				LabelExists("app").LabelNotExists("canary"),
+		},
+		{
+			"limit offset timeout",
+			ListOptionsBuilder().Limit(10).Offset(20).Timeout(time.Minute),
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			expect := test.opts.Options()
+			restored, err := FromListOptions(expect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := restored.Options(); !reflect.DeepEqual(got, expect) {
+				t.Errorf("Unexpect restored options: %+v, expect: %+v", got, expect)
+			}
+
+			restored, err = FromClientListOptions(test.opts.Build())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := restored.Options(); !reflect.DeepEqual(got, expect) {
+				t.Errorf("Unexpect restored client options: %+v, expect: %+v", got, expect)
+			}
+		})
+	}
+}
+
+func TestFromListOptionsWithRestrictions(t *testing.T) {
+	opts, err := FromListOptions(metaV1.ListOptions{
+		LabelSelector: "app=nginx,env notin (test),search.clusterpedia.io/namespaces=default",
+		Limit:         5,
+		Continue:      "opaque-token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clusters := opts.LabelValues(constants.SearchLabelClusters); len(clusters) == 0 {
+		opts.Clusters("tenant-a")
+	}
+
+	options := opts.Options()
+	expect := "app=nginx,env notin (test),search.clusterpedia.io/clusters=tenant-a,search.clusterpedia.io/namespaces=default"
+	if options.LabelSelector != expect {
+		t.Errorf("Unexpect label selector: %s, expect: %s", options.LabelSelector, expect)
+	}
+	if options.Limit != 5 || options.Continue != "opaque-token" {
+		t.Errorf("Unexpect limit and continue: %d, %s", options.Limit, options.Continue)
+	}
+
+	// the selector of the user isn't mixed with the search labels
+	if namespaces := opts.LabelValues(constants.SearchLabelNamespaces); !reflect.DeepEqual(namespaces, []string{"default"}) {
+		t.Errorf("Unexpect namespaces: %v", namespaces)
+	}
+	if values := opts.LabelValues("app"); len(values) != 0 {
+		t.Errorf("Unexpect user label in the search labels: %v", values)
+	}
+}
+
+func TestRestrictScopes(t *testing.T) {
+	testCase := []struct {
+		name          string
+		labelSelector string
+		expect        string
+		expectInvalid bool
+	}{
+		{
+			"clusters outside the allowed clusters are removed",
+			"search.clusterpedia.io/clusters in (tenant-a,tenant-b,other),search.clusterpedia.io/namespaces in (default,kube-system)",
+			"search.clusterpedia.io/clusters in (tenant-a,tenant-b),search.clusterpedia.io/namespaces=default",
+			false,
+		},
+		{
+			"allowed clusters are set if no cluster is requested",
+			"app=nginx",
+			"app=nginx,search.clusterpedia.io/clusters in (tenant-a,tenant-b),search.clusterpedia.io/namespaces=default",
+			false,
+		},
+		{
+			"none of the clusters is allowed",
+			"search.clusterpedia.io/clusters=other",
+			invalidSelector,
+			true,
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			opts, err := FromListOptions(metaV1.ListOptions{LabelSelector: test.labelSelector})
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts.RestrictClusters("tenant-a", "tenant-b").RestrictNamespaces("default")
+
+			if ls := opts.Options().LabelSelector; ls != test.expect {
+				t.Errorf("Unexpect label selector: %s, expect: %s", ls, test.expect)
+			}
+			if err := opts.Validate(); (err != nil) != test.expectInvalid {
+				t.Errorf("Unexpect validate error: %v", err)
+			}
+			if _, err := labels.Parse(opts.Options().LabelSelector); (err != nil) != test.expectInvalid {
+				t.Errorf("Expect the label selector is rejected if none of the clusters is allowed, error: %v", err)
+			}
+		})
+	}
+
+	opts := ListOptionsBuilder().Clusters("cluster-1").SetClusters("cluster-2", "cluster-3").Namespaces("a").SetNamespaces()
+	if ls := opts.Options().LabelSelector; ls != "search.clusterpedia.io/clusters in (cluster-2,cluster-3)" {
+		t.Errorf("Unexpect label selector: %s", ls)
+	}
+}
+
+func TestRestrictBypass(t *testing.T) {
+	victim := labels.SelectorFromSet(labels.Set{constants.SearchLabelClusters: "victim"})
+	testCase := []struct {
+		name   string
+		opts   ListOptionsInterface
+		expect string
+	}{
+		{
+			"no cluster is allowed",
+			ListOptionsBuilder().RestrictClusters(),
+			invalidSelector,
+		},
+		{
+			"no namespace is allowed",
+			ListOptionsBuilder().Namespaces("default").RestrictNamespaces(),
+			invalidSelector,
+		},
+		{
+			"clusters set after the restriction",
+			ListOptionsBuilder().RestrictClusters("tenant-a").Clusters("victim", "tenant-a"),
+			"search.clusterpedia.io/clusters=tenant-a",
+		},
+		{
+			"intersected restrictions",
+			ListOptionsBuilder().RestrictClusters("tenant-a", "tenant-b").RestrictClusters("tenant-b", "tenant-c"),
+			"search.clusterpedia.io/clusters=tenant-b",
+		},
+		{
+			"cluster of the selector",
+			ListOptionsBuilder().Selector(victim).RestrictClusters("tenant-a"),
+			invalidSelector,
+		},
+		{
+			"cluster of the selector set after the restriction",
+			ListOptionsBuilder().RestrictClusters("tenant-a").Selector(victim),
+			invalidSelector,
+		},
+		{
+			"cluster injected by a label value",
+			ListOptionsBuilder().RestrictClusters("tenant-a").LabelSelector("app", []string{"x,search.clusterpedia.io/clusters=victim"}),
+			invalidSelector,
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			ls := test.opts.Options().LabelSelector
+			if ls != test.expect {
+				t.Errorf("Unexpect label selector: %s, expect: %s", ls, test.expect)
+			}
+			if err := test.opts.Validate(); (err != nil) != (test.expect == invalidSelector) {
+				t.Errorf("Unexpect validate error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFromClientListOptions(t *testing.T) {
+	opts, err := FromClientListOptions(&client.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{constants.SearchLabelClusters: "cluster-1", "app": "nginx"}),
+		Limit:         10,
+		Continue:      "10",
+		Raw:           &metaV1.ListOptions{LabelSelector: "ignored=true", ResourceVersion: "0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := opts.Options()
+	if options.LabelSelector != "app=nginx,search.clusterpedia.io/clusters=cluster-1" {
+		t.Errorf("Unexpect label selector: %s", options.LabelSelector)
+	}
+	if options.Limit != 10 || options.Continue != "10" || options.ResourceVersion != "0" {
+		t.Errorf("Unexpect options: %+v", options)
+	}
+}
+
+func TestFromListOptionsError(t *testing.T) {
+	for _, options := range []metaV1.ListOptions{
+		{LabelSelector: "app in (a"},
+		{LabelSelector: "search.clusterpedia.io/clusters!=cluster-1"},
+		{LabelSelector: "search.clusterpedia.io/owner-uid in (a,b)"},
+		{LabelSelector: "search.clusterpedia.io/owner-seniority=first"},
+		{LabelSelector: "search.clusterpedia.io/since=yesterday"},
+		{FieldSelector: "status.replicas>1"},
+		{FieldSelector: "metadata.annotations['app.io/x]=a"},
+	} {
+		if _, err := FromListOptions(options); err == nil {
+			t.Errorf("Expect error for %+v", options)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/clusterpedia-io/api/clusterpedia/fields"
+
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// FieldPath builds the enhanced field paths of clusterpedia, the names which can't be
+// written after '.' are wrapped by brackets, e.g.
+//
+//	NewFieldPath("metadata").Child("annotations").Child("app.io/x")   // metadata.annotations['app.io/x']
+//	NewFieldPath("spec").Child("containers").Index(0).Child("image")  // spec.containers[0].image
+//
+// The paths can be written after the field. prefix of the tools/query DSL as they are,
+// e.g. field.metadata.annotations['app.io/x']=a.
+type FieldPath struct {
+	path string
+}
+
+var simpleFieldName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func NewFieldPath(name string, moreNames ...string) *FieldPath {
+	p := (&FieldPath{}).Child(name)
+	for _, name := range moreNames {
+		p = p.Child(name)
+	}
+	return p
+}
+
+// Child returns a new path with the name appended.
+func (p *FieldPath) Child(name string) *FieldPath {
+	switch {
+	case simpleFieldName.MatchString(name) && p.path == "":
+		return &FieldPath{path: name}
+	case simpleFieldName.MatchString(name):
+		return &FieldPath{path: p.path + "." + name}
+	case !strings.Contains(name, "'"):
+		return &FieldPath{path: p.path + "['" + name + "']"}
+	}
+	// the parser of clusterpedia doesn't support escape characters,
+	// a name with both ' and " is reported as an invalid field.
+	return &FieldPath{path: p.path + `["` + name + `"]`}
+}
+
+// Index returns a new path with the list index appended.
+func (p *FieldPath) Index(index int) *FieldPath {
+	return &FieldPath{path: p.path + "[" + strconv.Itoa(index) + "]"}
+}
+
+func (p *FieldPath) String() string {
+	return p.path
+}
+
+// fieldValueSpecialChars can't be used in the values of the field selector,
+// since the lexer of clusterpedia splits the identifiers at these characters.
+const fieldValueSpecialChars = " \t\r\n\x00=!(),<>"
+
+type fieldRequirement struct {
+	field    string
+	operator selection.Operator
+	values   []string
+}
+
+func (opts *listOptions) FieldNotIn(field string, values []string) ListOptionsInterface {
+	opts.fieldExclusions[field] = append(opts.fieldExclusions[field], values...)
+	return opts
+}
+
+func (opts *listOptions) FieldExists(field string) ListOptionsInterface {
+	opts.fieldExistence[field] = true
+	return opts
+}
+
+func (opts *listOptions) FieldNotExists(field string) ListOptionsInterface {
+	opts.fieldExistence[field] = false
+	return opts
+}
+
+// fieldRequirements returns the requirements of the field selector sorted by the fields.
+func (opts *listOptions) fieldRequirements() []fieldRequirement {
+	var requirements []fieldRequirement
+	for field, values := range opts.fieldSelector {
+		op := selection.Equals
+		if len(values) > 1 {
+			op = selection.In
+		}
+		requirements = append(requirements, fieldRequirement{field, op, values})
+	}
+	for field, values := range opts.fieldExclusions {
+		op := selection.NotEquals
+		if len(values) > 1 {
+			op = selection.NotIn
+		}
+		requirements = append(requirements, fieldRequirement{field, op, values})
+	}
+	for field, exists := range opts.fieldExistence {
+		op := selection.Exists
+		if !exists {
+			op = selection.DoesNotExist
+		}
+		requirements = append(requirements, fieldRequirement{field: field, operator: op})
+	}
+
+	order := map[selection.Operator]int{
+		selection.Equals: 0, selection.In: 0, selection.NotEquals: 1, selection.NotIn: 1,
+		selection.Exists: 2, selection.DoesNotExist: 2,
+	}
+	sort.Slice(requirements, func(i, j int) bool {
+		if requirements[i].field != requirements[j].field {
+			return requirements[i].field < requirements[j].field
+		}
+		return order[requirements[i].operator] < order[requirements[j].operator]
+	})
+	return requirements
+}
+
+func newFieldRequirement(r fieldRequirement) (*fields.Requirement, error) {
+	for _, v := range r.values {
+		if strings.ContainsAny(v, fieldValueSpecialChars) {
+			return nil, fmt.Errorf("value %q must not contain any of %q", v, fieldValueSpecialChars)
+		}
+	}
+	requirement, err := fields.NewRequirement(r.field, r.operator, append([]string(nil), r.values...))
+	if err != nil {
+		return nil, err
+	}
+	return requirement, nil
+}
+
+// fieldSelectorString serializes the field selector in the syntax of the field selector parser of clusterpedia,
+// the field selector which has an invalid requirement is replaced by invalidSelector, see Options.
+func (opts *listOptions) fieldSelectorString() string {
+	var requirements []string
+	for _, r := range opts.fieldRequirements() {
+		requirement, err := newFieldRequirement(r)
+		if err != nil {
+			return invalidSelector
+		}
+		requirements = append(requirements, requirement.String())
+	}
+	return strings.Join(requirements, ",")
+}
+
+// FieldRequirement is a requirement of the field selector returned by ParseFieldSelector.
+type FieldRequirement struct {
+	Field    string
+	Operator selection.Operator
+	Values   []string
+}
+
+// ParseFieldSelector parses the field selector in the syntax of the field selector parser of clusterpedia,
+// the fields may be the enhanced field paths built by FieldPath, which are not supported by fields.ParseSelector.
+func ParseFieldSelector(selector string) ([]FieldRequirement, error) {
+	requirements, err := parseFieldSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]FieldRequirement, 0, len(requirements))
+	for _, r := range requirements {
+		result = append(result, FieldRequirement{Field: r.field, Operator: r.operator, Values: r.values})
+	}
+	return result, nil
+}
+
+// parseFieldSelector parses the field selector into the field requirements,
+// the key of a requirement is not exported by the clusterpedia parser, so it is
+// cut from the serialized requirement, which always starts with the key.
+func parseFieldSelector(selector string) ([]fieldRequirement, error) {
+	parsed, err := fields.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	requirements, _ := parsed.Requirements()
+	result := make([]fieldRequirement, 0, len(requirements))
+	for i := range requirements {
+		r := &requirements[i]
+		key := strings.TrimPrefix(r.String(), "!")
+		if index := strings.IndexAny(key, " =!<>"); index != -1 {
+			key = key[:index]
+		}
+		result = append(result, fieldRequirement{field: key, operator: r.Operator(), values: r.Values().List()})
+	}
+	return result, nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/clusterpedia-io/api/clusterpedia/fields"
+)
+
+func TestFieldPath(t *testing.T) {
+	testCase := []struct {
+		path   *FieldPath
+		expect string
+	}{
+		{NewFieldPath("metadata", "name"), "metadata.name"},
+		{NewFieldPath("metadata", "annotations", "app.io/x"), "metadata.annotations['app.io/x']"},
+		{NewFieldPath("metadata").Child("labels").Child("app.kubernetes.io/name"), "metadata.labels['app.kubernetes.io/name']"},
+		{NewFieldPath("spec").Child("containers").Index(0).Child("image"), "spec.containers[0].image"},
+		{NewFieldPath("status").Child("conditions").Index(1).Child("last_probe-time"), "status.conditions[1].last_probe-time"},
+		{NewFieldPath("a.b"), "['a.b']"},
+		{NewFieldPath("data", "it's"), `data["it's"]`},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.expect, func(t *testing.T) {
+			if path := test.path.String(); path != test.expect {
+				t.Errorf("Unexpect field path: %s, expect: %s", path, test.expect)
+			}
+		})
+	}
+}
+
+// TestFieldSelectorGolden compares the field selectors with the known-good strings,
+// which are accepted by the field selector parser of clusterpedia.
+func TestFieldSelectorGolden(t *testing.T) {
+	annotation := NewFieldPath("metadata", "annotations", "app.io/x").String()
+	image := NewFieldPath("spec").Child("containers").Index(0).Child("image").String()
+
+	testCase := []struct {
+		name   string
+		opts   ListOptionsInterface
+		expect string
+	}{
+		{
+			"equals",
+			ListOptionsBuilder().FieldSelector("status.phase", []string{"Running"}),
+			"status.phase=Running",
+		},
+		{
+			"in",
+			ListOptionsBuilder().FieldSelector("status.phase", []string{"Running"}).FieldSelector("status.phase", []string{"Pending"}),
+			"status.phase in (Pending,Running)",
+		},
+		{
+			"not equals",
+			ListOptionsBuilder().FieldNotIn("status.phase", []string{"Failed"}),
+			"status.phase!=Failed",
+		},
+		{
+			"notin",
+			ListOptionsBuilder().FieldNotIn("status.phase", []string{"Succeeded", "Failed"}),
+			"status.phase notin (Failed,Succeeded)",
+		},
+		{
+			"exists",
+			ListOptionsBuilder().FieldExists(annotation).FieldNotExists("metadata.deletionTimestamp"),
+			"metadata.annotations['app.io/x'],!metadata.deletionTimestamp",
+		},
+		{
+			"bracketed paths",
+			ListOptionsBuilder().FieldSelector(annotation, []string{"enabled"}).FieldNotIn(image, []string{"nginx:1.25"}),
+			"metadata.annotations['app.io/x']=enabled,spec.containers[0].image!=nginx:1.25",
+		},
+		{
+			"same field",
+			ListOptionsBuilder().FieldNotExists("status.reason").FieldNotIn("status.phase", []string{"Failed"}).
+				FieldSelector("status.phase", []string{"Running", "Pending"}),
+			"status.phase in (Pending,Running),status.phase!=Failed,!status.reason",
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			options, err := test.opts.OptionsE()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if options.FieldSelector != test.expect {
+				t.Errorf("Unexpect field selector: %s, expect: %s", options.FieldSelector, test.expect)
+			}
+			if _, err := fields.Parse(options.FieldSelector); err != nil {
+				t.Errorf("Field selector %q isn't accepted by clusterpedia: %v", options.FieldSelector, err)
+			}
+
+			restored, err := FromListOptions(options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := restored.Options(); !reflect.DeepEqual(got, options) {
+				t.Errorf("Unexpect restored options: %+v, expect: %+v", got, options)
+			}
+		})
+	}
+}
+
+func TestInvalidFieldSelector(t *testing.T) {
+	testCase := []struct {
+		name string
+		opts ListOptionsInterface
+	}{
+		{"unclosed bracket", ListOptionsBuilder().FieldSelector("metadata.annotations['app.io/x'", []string{"a"})},
+		{"list as last field", ListOptionsBuilder().FieldExists("spec.containers[0]")},
+		{"value with space", ListOptionsBuilder().FieldSelector("metadata.name", []string{"a b"})},
+		{"value with comma", ListOptionsBuilder().FieldNotIn("metadata.name", []string{"a,b"})},
+		{"both quotes in the name", ListOptionsBuilder().FieldExists(NewFieldPath("data", `it's "x"`).String())},
+		{
+			"injected requirement",
+			ListOptionsBuilder().FieldSelector("status.phase", []string{"Running"}).
+				FieldSelector("metadata.name", []string{"a,metadata.namespace!=b"}),
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.opts.Validate(); err == nil {
+				t.Errorf("Expect validate error")
+			}
+			fieldSelector := test.opts.Options().FieldSelector
+			if fieldSelector != invalidSelector {
+				t.Errorf("Unexpect field selector: %s, expect: %s", fieldSelector, invalidSelector)
+			}
+			if _, err := fields.Parse(fieldSelector); err == nil {
+				t.Errorf("Expect the field selector isn't accepted by clusterpedia")
+			}
+		})
+	}
+}
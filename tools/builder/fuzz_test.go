@@ -0,0 +1,72 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/clusterpedia-io/api/clusterpedia/fields"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func FuzzListOptions(f *testing.F) {
+	f.Add("cluster-1", "default", "app", "nginx", "status.phase", "Running", 0, 10, int64(30*time.Second))
+	f.Add("cluster 1", "", "-app", "", "", "", -1, -1, int64(-time.Second))
+	f.Add("0", "0", "0", "0", "1", "\x00", 0, 10, int64(30*time.Second))
+	f.Add("a,b", "kube-system", "app.kubernetes.io/name", "a=b", "metadata.name", "in (a,b)", 1<<31, 0, int64(time.Millisecond))
+
+	f.Fuzz(func(t *testing.T, cluster, namespace, labelKey, labelValue, fieldKey, fieldValue string, offset, limit int, timeout int64) {
+		opts := ListOptionsBuilder().
+			Clusters(cluster).
+			Namespaces(namespace).
+			LabelSelector(labelKey, []string{labelValue}).
+			FieldSelector(fieldKey, []string{fieldValue}).
+			FieldNotIn(labelKey, []string{fieldValue, labelValue}).
+			OrderBy(labelKey, true).
+			Offset(offset).
+			Limit(limit).
+			Timeout(time.Duration(timeout))
+
+		// Options must never panic, and returns parsable selectors for the valid options
+		options := opts.Options()
+		validated, err := opts.OptionsE()
+		if err != nil {
+			return
+		}
+		if _, err := labels.Parse(options.LabelSelector); err != nil {
+			t.Errorf("Options returns an invalid label selector %q: %v", options.LabelSelector, err)
+		}
+		if _, err := fields.Parse(options.FieldSelector); err != nil {
+			t.Errorf("Options returns an invalid field selector %q: %v", options.FieldSelector, err)
+		}
+		if validated.LabelSelector != options.LabelSelector || validated.FieldSelector != options.FieldSelector {
+			t.Errorf("OptionsE returns different selectors: %+v, Options: %+v", validated, options)
+		}
+
+		// all of the labels of valid options are kept in the selector
+		selector, _ := labels.Parse(validated.LabelSelector)
+		requirements, _ := selector.Requirements()
+		if expect := len(opts.(*listOptions).labels); len(requirements) != expect {
+			t.Errorf("Unexpect requirements of %q: %d, expect: %d", validated.LabelSelector, len(requirements), expect)
+		}
+		if validated.Limit < 0 || (validated.TimeoutSeconds != nil && *validated.TimeoutSeconds <= 0) {
+			t.Errorf("Unexpect limit or timeout of valid options: %+v", validated)
+		}
+	})
+}
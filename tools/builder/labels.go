@@ -0,0 +1,120 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+type labelRequirement struct {
+	label    string
+	operator selection.Operator
+	values   []string
+}
+
+// MatchLabels adds the labels to the label selector like LabelSelector,
+// the values of a label which is set more than once are combined with 'in'.
+func (opts *listOptions) MatchLabels(matchLabels map[string]string) ListOptionsInterface {
+	for label, value := range matchLabels {
+		opts.labels[label] = append(opts.labels[label], value)
+	}
+	return opts
+}
+
+func (opts *listOptions) LabelNotIn(label string, values []string) ListOptionsInterface {
+	opts.labelExclusions[label] = append(opts.labelExclusions[label], values...)
+	return opts
+}
+
+func (opts *listOptions) LabelExists(label string) ListOptionsInterface {
+	opts.labelExistence[label] = true
+	return opts
+}
+
+func (opts *listOptions) LabelNotExists(label string) ListOptionsInterface {
+	opts.labelExistence[label] = false
+	return opts
+}
+
+// labelRequirements returns the requirements of the labels set by the builder sorted by the labels,
+// the requirements of the selectors set by Selector are not included.
+func (opts *listOptions) labelRequirements() []labelRequirement {
+	var requirements []labelRequirement
+	matched := sets.KeySet(opts.labels).Union(sets.KeySet(opts.restrictions))
+	for label := range matched {
+		values, _ := opts.labelValues(label)
+		op := selection.Equals
+		if len(values) > 1 {
+			op = selection.In
+		}
+		requirements = append(requirements, labelRequirement{label, op, values})
+	}
+	for label, values := range opts.labelExclusions {
+		op := selection.NotEquals
+		if len(values) > 1 {
+			op = selection.NotIn
+		}
+		requirements = append(requirements, labelRequirement{label, op, values})
+	}
+	for label, exists := range opts.labelExistence {
+		op := selection.Exists
+		if !exists {
+			op = selection.DoesNotExist
+		}
+		requirements = append(requirements, labelRequirement{label: label, operator: op})
+	}
+
+	order := map[selection.Operator]int{
+		selection.Equals: 0, selection.In: 0, selection.NotEquals: 1, selection.NotIn: 1,
+		selection.Exists: 2, selection.DoesNotExist: 2,
+	}
+	sort.Slice(requirements, func(i, j int) bool {
+		if requirements[i].label != requirements[j].label {
+			return requirements[i].label < requirements[j].label
+		}
+		return order[requirements[i].operator] < order[requirements[j].operator]
+	})
+	return requirements
+}
+
+func newLabelRequirement(r labelRequirement) (*labels.Requirement, error) {
+	if isSearchLabel(r.label) && r.operator != selection.Equals && r.operator != selection.In {
+		return nil, fmt.Errorf("operator %q is not supported by the clusterpedia search labels", r.operator)
+	}
+	if (r.operator == selection.Equals || r.operator == selection.In) && len(r.values) == 0 {
+		return nil, fmt.Errorf("no value is set or allowed")
+	}
+	return labels.NewRequirement(r.label, r.operator, append([]string(nil), r.values...))
+}
+
+// invalidSelector replaces the label or field selector which has an invalid requirement, it is rejected
+// by the parsers of clusterpedia, so that the query fails instead of querying more resources than expected.
+// The values of the invalid requirements are never sent, since they aren't escaped,
+// e.g. a value with ',' would add another requirement to the selector.
+const invalidSelector = "()"
+
+// isSearchLabel returns true for the labels which are handled by clusterpedia
+// instead of being matched with the labels of the resources.
+func isSearchLabel(label string) bool {
+	return strings.Contains(label, "clusterpedia.io/")
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"testing"
+
+	"github.com/clusterpedia-io/client-go/constants"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestLabelRequirements(t *testing.T) {
+	testCase := []struct {
+		name   string
+		opts   ListOptionsInterface
+		expect string
+	}{
+		{
+			"not in",
+			ListOptionsBuilder().LabelNotIn("tier", []string{"cache"}).LabelNotIn("tier", []string{"db"}),
+			"tier notin (cache,db)",
+		},
+		{
+			"not equals",
+			ListOptionsBuilder().LabelNotIn("tier", []string{"cache"}),
+			"tier!=cache",
+		},
+		{
+			"existence",
+			ListOptionsBuilder().LabelExists("app").LabelNotExists("canary"),
+			"app,!canary",
+		},
+		{
+			"match labels",
+			ListOptionsBuilder().MatchLabels(map[string]string{"app": "nginx", "tier": "frontend"}).
+				LabelSelector("tier", []string{"backend"}),
+			"app=nginx,tier in (backend,frontend)",
+		},
+		{
+			"same label",
+			ListOptionsBuilder().LabelNotExists("app").LabelNotIn("app", []string{"redis"}).LabelSelector("app", []string{"nginx"}),
+			"app=nginx,app!=redis,!app",
+		},
+		{
+			"with search labels",
+			ListOptionsBuilder().Clusters("cluster-1").LabelNotIn("env", []string{"test"}).LabelExists("app"),
+			"app,env!=test,search.clusterpedia.io/clusters=cluster-1",
+		},
+		{
+			"with selectors",
+			ListOptionsBuilder().LabelNotIn("env", []string{"test"}).
+				Selector(labels.SelectorFromSet(labels.Set{"app": "nginx"})).
+				Selector(labels.SelectorFromSet(labels.Set{"tier": "frontend"})),
+			"app=nginx,env!=test,tier=frontend",
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			options, err := test.opts.OptionsE()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if options.LabelSelector != test.expect {
+				t.Errorf("Unexpect label selector: %s, expect: %s", options.LabelSelector, test.expect)
+			}
+			if _, err := labels.Parse(options.LabelSelector); err != nil {
+				t.Errorf("Invalid label selector %q: %v", options.LabelSelector, err)
+			}
+		})
+	}
+}
+
+func TestInvalidLabelRequirements(t *testing.T) {
+	testCase := []struct {
+		name string
+		opts ListOptionsInterface
+	}{
+		{"search label not in", ListOptionsBuilder().LabelNotIn(constants.SearchLabelNamespaces, []string{"kube-system"})},
+		{"search label exists", ListOptionsBuilder().LabelExists(constants.SearchLabelOwnerUID)},
+		{"invalid label", ListOptionsBuilder().LabelNotExists("-app")},
+		{"invalid value of not in", ListOptionsBuilder().LabelNotIn("app", []string{"a b", "c"})},
+		{"invalid cluster with namespaces", ListOptionsBuilder().Clusters("bad cluster").Namespaces("ns")},
+		{
+			"injected search label",
+			ListOptionsBuilder().Clusters("tenant").LabelSelector("app", []string{"x,search.clusterpedia.io/clusters=victim"}),
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.opts.Validate(); err == nil {
+				t.Errorf("Expect validate error")
+			}
+			// the selector is rejected instead of widened, and the invalid values are never sent
+			labelSelector := test.opts.Options().LabelSelector
+			if labelSelector != invalidSelector {
+				t.Errorf("Unexpect label selector: %s, expect: %s", labelSelector, invalidSelector)
+			}
+			if _, err := labels.Parse(labelSelector); err == nil {
+				t.Errorf("Expect the label selector isn't accepted by clusterpedia")
+			}
+		})
+	}
+}
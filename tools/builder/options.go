@@ -25,9 +25,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 type ListOptionsInterface interface {
@@ -35,18 +36,37 @@ type ListOptionsInterface interface {
 	Names(names ...string) ListOptionsInterface
 	FuzzyNames(names ...string) ListOptionsInterface
 	Namespaces(namespaces ...string) ListOptionsInterface
+	SetClusters(clusters ...string) ListOptionsInterface
+	RestrictClusters(allowed ...string) ListOptionsInterface
+	SetNamespaces(namespaces ...string) ListOptionsInterface
+	RestrictNamespaces(allowed ...string) ListOptionsInterface
 	Limit(limit int) ListOptionsInterface
 	Offset(offset int) ListOptionsInterface
 	OrderBy(field string, desc ...bool) ListOptionsInterface
 	Timeout(timeout time.Duration) ListOptionsInterface
 	RemainingCount() ListOptionsInterface
+	WithContinue() ListOptionsInterface
 	OwnerUID(uid string) ListOptionsInterface
 	OwnerName(name string) ListOptionsInterface
 	OwnerSeniority(ownerSeniority int) ListOptionsInterface
+	OwnerGroupResource(gr schema.GroupResource) ListOptionsInterface
+	Owner(owner OwnerQuery) ListOptionsInterface
+	Since(since time.Time) ListOptionsInterface
+	Before(before time.Time) ListOptionsInterface
 	LabelSelector(field string, values []string) ListOptionsInterface
+	MatchLabels(matchLabels map[string]string) ListOptionsInterface
+	LabelNotIn(label string, values []string) ListOptionsInterface
+	LabelExists(label string) ListOptionsInterface
+	LabelNotExists(label string) ListOptionsInterface
 	Selector(ls labels.Selector) ListOptionsInterface
 	FieldSelector(field string, values []string) ListOptionsInterface
+	FieldNotIn(field string, values []string) ListOptionsInterface
+	FieldExists(field string) ListOptionsInterface
+	FieldNotExists(field string) ListOptionsInterface
+	LabelValues(label string) []string
+	Validate() error
 	Options() metav1.ListOptions
+	OptionsE() (metav1.ListOptions, error)
 	Build() *client.ListOptions
 }
 
@@ -54,14 +74,42 @@ type listOptions struct {
 	options       metav1.ListOptions
 	labels        map[string][]string
 	labelSelector labels.Selector
-	fieldSelector map[string][]string
+	// labelExclusions are the values excluded by '!=' or 'notin'
+	labelExclusions map[string][]string
+	// labelExistence is true for the labels must exist and false for the labels must not exist
+	labelExistence map[string]bool
+	fieldSelector  map[string][]string
+	// fieldExclusions are the values excluded by '!=' or 'notin'
+	fieldExclusions map[string][]string
+	// fieldExistence is true for the fields must exist and false for the fields must not exist
+	fieldExistence map[string]bool
+
+	since  time.Time
+	before time.Time
+
+	// owner is set by Owner, it is only used to validate the owner query
+	owner *OwnerQuery
+
+	// restrictions are the allowed values of the search labels restricted by RestrictClusters and RestrictNamespaces,
+	// they are applied when the options are built, so the values added later are restricted too.
+	restrictions map[string]sets.Set[string]
+
+	// invalid records the invalid arguments of Limit, Offset and Timeout by the field,
+	// a later valid argument of the same field removes the error.
+	invalid map[string]*field.Error
 }
 
 func ListOptionsBuilder() ListOptionsInterface {
 	return &listOptions{
-		options:       metav1.ListOptions{},
-		labels:        make(map[string][]string),
-		fieldSelector: make(map[string][]string),
+		options:         metav1.ListOptions{},
+		labels:          make(map[string][]string),
+		labelExclusions: make(map[string][]string),
+		labelExistence:  make(map[string]bool),
+		fieldSelector:   make(map[string][]string),
+		fieldExclusions: make(map[string][]string),
+		fieldExistence:  make(map[string]bool),
+		restrictions:    make(map[string]sets.Set[string]),
+		invalid:         make(map[string]*field.Error),
 	}
 }
 
@@ -112,6 +160,22 @@ func (opts *listOptions) OwnerSeniority(ownerSeniority int) ListOptionsInterface
 	return opts
 }
 
+func (opts *listOptions) Since(since time.Time) ListOptionsInterface {
+	if !since.IsZero() {
+		opts.since = since
+		opts.labels[constants.SearchLabelSince] = []string{formatTime(since)}
+	}
+	return opts
+}
+
+func (opts *listOptions) Before(before time.Time) ListOptionsInterface {
+	if !before.IsZero() {
+		opts.before = before
+		opts.labels[constants.SearchLabelBefore] = []string{formatTime(before)}
+	}
+	return opts
+}
+
 func (opts *listOptions) Namespaces(namespaces ...string) ListOptionsInterface {
 	if len(namespaces) > 0 {
 		opts.labels[constants.SearchLabelNamespaces] =
@@ -120,16 +184,106 @@ func (opts *listOptions) Namespaces(namespaces ...string) ListOptionsInterface {
 	return opts
 }
 
+// SetClusters replaces the clusters set before, the resources of all of the clusters are queried
+// if clusters is empty.
+func (opts *listOptions) SetClusters(clusters ...string) ListOptionsInterface {
+	opts.setSearchLabel(constants.SearchLabelClusters, clusters)
+	return opts
+}
+
+// RestrictClusters restricts the clusters to the allowed clusters, e.g. the clusters of a tenant,
+// the clusters which are not allowed are removed, and the allowed clusters are set if no cluster is set.
+// The restriction also applies to the clusters set later, and the restrictions set more than once are intersected.
+// If none of the clusters is allowed, or the selector set by Selector requires the clusters,
+// the options are invalid and rejected by clusterpedia.
+func (opts *listOptions) RestrictClusters(allowed ...string) ListOptionsInterface {
+	opts.restrictSearchLabel(constants.SearchLabelClusters, allowed)
+	return opts
+}
+
+// SetNamespaces replaces the namespaces set before like SetClusters.
+func (opts *listOptions) SetNamespaces(namespaces ...string) ListOptionsInterface {
+	opts.setSearchLabel(constants.SearchLabelNamespaces, namespaces)
+	return opts
+}
+
+// RestrictNamespaces restricts the namespaces to the allowed namespaces like RestrictClusters.
+func (opts *listOptions) RestrictNamespaces(allowed ...string) ListOptionsInterface {
+	opts.restrictSearchLabel(constants.SearchLabelNamespaces, allowed)
+	return opts
+}
+
+func (opts *listOptions) setSearchLabel(label string, values []string) {
+	if len(values) == 0 {
+		delete(opts.labels, label)
+		return
+	}
+	opts.labels[label] = append([]string(nil), values...)
+}
+
+func (opts *listOptions) restrictSearchLabel(label string, allowed []string) {
+	allowedSet := sets.New(allowed...)
+	if restricted, ok := opts.restrictions[label]; ok {
+		allowedSet = allowedSet.Intersection(restricted)
+	}
+	opts.restrictions[label] = allowedSet
+}
+
+// labelValues returns the values of the label set by the builder with the restriction of the label applied,
+// the allowed values are returned if the restricted label isn't set.
+func (opts *listOptions) labelValues(label string) ([]string, bool) {
+	values, ok := opts.labels[label]
+	allowed, restricted := opts.restrictions[label]
+	if !restricted {
+		return values, ok
+	}
+	if len(values) == 0 {
+		return sets.List(allowed), true
+	}
+
+	// the empty values are kept, so that the label isn't treated as not set
+	result := []string{}
+	for _, value := range values {
+		if allowed.Has(value) {
+			result = append(result, value)
+		}
+	}
+	return result, true
+}
+
+// restrictedSelectorLabels returns the restricted search labels required by the selector set by Selector,
+// they would be sent besides the restricted values, so the options are invalid.
+func (opts *listOptions) restrictedSelectorLabels() []string {
+	if opts.labelSelector == nil || len(opts.restrictions) == 0 {
+		return nil
+	}
+	var keys []string
+	requirements, _ := opts.labelSelector.Requirements()
+	for _, r := range requirements {
+		if _, ok := opts.restrictions[r.Key()]; ok {
+			keys = append(keys, r.Key())
+		}
+	}
+	return keys
+}
+
 func (opts *listOptions) Limit(limit int) ListOptionsInterface {
-	if limit > 0 {
+	delete(opts.invalid, "limit")
+	switch {
+	case limit > 0:
 		opts.options.Limit = int64(limit)
+	case limit < 0:
+		opts.invalid["limit"] = field.Invalid(field.NewPath("limit"), limit, "must not be negative")
 	}
 	return opts
 }
 
 func (opts *listOptions) Offset(offset int) ListOptionsInterface {
+	delete(opts.invalid, "offset")
 	if offset >= 0 {
 		opts.options.Continue = strconv.Itoa(offset)
+	} else {
+		opts.invalid["offset"] = field.Invalid(field.NewPath("offset"), offset, "must not be negative")
 	}
 	return opts
 }
@@ -149,10 +303,17 @@ func (opts *listOptions) OrderBy(field string, desc ...bool) ListOptionsInterfac
 	return opts
 }
 
+// Timeout sets the timeout seconds of the request, the fraction of a second is truncated.
 func (opts *listOptions) Timeout(timeout time.Duration) ListOptionsInterface {
-	if timeout > 0 {
-		timeoutSeconds := int64(timeout * time.Second)
+	delete(opts.invalid, "timeout")
+	switch {
+	case timeout >= time.Second:
+		timeoutSeconds := int64(timeout / time.Second)
 		opts.options.TimeoutSeconds = &timeoutSeconds
+	case timeout < 0:
+		opts.invalid["timeout"] = field.Invalid(field.NewPath("timeout"), timeout.String(), "must not be negative")
+	case timeout > 0:
+		opts.invalid["timeout"] = field.Invalid(field.NewPath("timeout"), timeout.String(), "must be at least 1s")
 	}
 	return opts
 }
@@ -164,14 +325,34 @@ func (opts *listOptions) RemainingCount() ListOptionsInterface {
 	return opts
 }
 
+func (opts *listOptions) WithContinue() ListOptionsInterface {
+	opts.labels[constants.SearchLabelWithContinue] = []string{strconv.FormatBool(true)}
+	return opts
+}
+
 func (opts *listOptions) LabelSelector(field string, values []string) ListOptionsInterface {
 	opts.labels[field] =
 		append(opts.labels[field], values...)
 	return opts
 }
 
+// Selector adds the requirements of the selector to the label selector,
+// the selectors set more than once are combined instead of replaced.
 func (opts *listOptions) Selector(ls labels.Selector) ListOptionsInterface {
-	opts.labelSelector = ls
+	if ls == nil {
+		return opts
+	}
+	if opts.labelSelector == nil {
+		opts.labelSelector = ls
+		return opts
+	}
+
+	requirements, selectable := ls.Requirements()
+	if !selectable {
+		opts.labelSelector = ls
+		return opts
+	}
+	opts.labelSelector = opts.labelSelector.Add(requirements...)
 	return opts
 }
 
@@ -181,45 +362,82 @@ func (opts *listOptions) FieldSelector(field string, values []string) ListOption
 	return opts
 }
 
+// LabelValues returns a copy of the values of the label set by the builder,
+// e.g. LabelValues(constants.SearchLabelClusters) returns the clusters to search.
+// The requirements of the selector set by Selector are not included.
+func (opts *listOptions) LabelValues(label string) []string {
+	values, _ := opts.labelValues(label)
+	return append([]string(nil), values...)
+}
+
+// Validate checks whether the options can be accepted by clusterpedia,
+// all of the problems are aggregated into the returned error.
+func (opts *listOptions) Validate() error {
+	var allErrs field.ErrorList
+	for _, name := range []string{"limit", "offset", "timeout"} {
+		if err := opts.invalid[name]; err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	path := field.NewPath("labelSelector")
+	for _, r := range opts.labelRequirements() {
+		if _, err := newLabelRequirement(r); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Key(r.label), r.values, err.Error()))
+		}
+	}
+	for _, label := range opts.restrictedSelectorLabels() {
+		allErrs = append(allErrs, field.Forbidden(path.Key(label), "the restricted search label must not be required by Selector"))
+	}
+	path = field.NewPath("fieldSelector")
+	for _, r := range opts.fieldRequirements() {
+		if _, err := newFieldRequirement(r); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Key(r.field), r.values, err.Error()))
+		}
+	}
+
+	allErrs = append(allErrs, opts.validateTimeRange()...)
+	allErrs = append(allErrs, opts.validateOwner()...)
+	return allErrs.ToAggregate()
+}
+
+// Options returns the list options, the label or field selector which has an invalid requirement is replaced
+// by a selector which is rejected by clusterpedia, instead of querying more resources than expected,
+// e.g. all of the clusters for an invalid cluster name. OptionsE is the supported way to find out the problems.
 func (opts *listOptions) Options() metav1.ListOptions {
 	ls := labels.Everything()
 	if opts.labelSelector != nil {
 		ls = opts.labelSelector
 	}
-	for label, values := range opts.labels {
-		var op selection.Operator
-		if len(values) > 1 {
-			op = selection.In
-		} else {
-			op = selection.Equals
-		}
-		r, _ := labels.NewRequirement(label, op, append([]string(nil), values...))
-		ls = ls.Add(*r)
+	opts.options.LabelSelector = ""
+	if len(opts.restrictedSelectorLabels()) > 0 {
+		opts.options.LabelSelector = invalidSelector
 	}
-	opts.options.LabelSelector = ls.String()
-
-	if len(opts.fieldSelector) == 0 {
-		opts.options.FieldSelector = fields.Everything().String()
-	} else {
-		requirements := make([]labels.Requirement, 0, len(opts.fieldSelector))
-		for label, values := range opts.fieldSelector {
-			var op selection.Operator
-			if len(values) > 1 {
-				op = selection.In
-			} else {
-				op = selection.Equals
-			}
-
-			r, _ := labels.NewRequirement(label, op, append([]string(nil), values...))
-			requirements = append(requirements, *r)
+	for _, r := range opts.labelRequirements() {
+		requirement, err := newLabelRequirement(r)
+		if err != nil {
+			opts.options.LabelSelector = invalidSelector
+			break
 		}
-		selector := labels.NewSelector()
-		selector = selector.Add(requirements...)
-		opts.options.FieldSelector = selector.String()
+		ls = ls.Add(*requirement)
+	}
+	if opts.options.LabelSelector == "" {
+		opts.options.LabelSelector = ls.String()
 	}
+
+	opts.options.FieldSelector = opts.fieldSelectorString()
 	return opts.options
 }
 
+// OptionsE validates the options and returns the list options if they are valid,
+// it is the supported way to build the options from the untrusted input.
+func (opts *listOptions) OptionsE() (metav1.ListOptions, error) {
+	if err := opts.Validate(); err != nil {
+		return metav1.ListOptions{}, err
+	}
+	return opts.Options(), nil
+}
+
 func (opts *listOptions) Build() *client.ListOptions {
 	opt := opts.Options()
 
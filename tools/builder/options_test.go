@@ -17,10 +17,16 @@ limitations under the License.
 package builder
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
 func TestListOptions(t *testing.T) {
@@ -82,3 +88,82 @@ func TestListOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestTimeout(t *testing.T) {
+	testCase := []struct {
+		timeout       time.Duration
+		expectSeconds *int64
+		expectErr     bool
+	}{
+		{30 * time.Second, int64Ptr(30), false},
+		{1500 * time.Millisecond, int64Ptr(1), false},
+		{0, nil, false},
+		{500 * time.Millisecond, nil, true},
+		{-time.Second, nil, true},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.timeout.String(), func(t *testing.T) {
+			opts := ListOptionsBuilder().Timeout(test.timeout)
+			if err := opts.Validate(); (err != nil) != test.expectErr {
+				t.Errorf("Unexpect validate error: %v, expect error: %v", err, test.expectErr)
+			}
+			if seconds := opts.Options().TimeoutSeconds; !reflect.DeepEqual(seconds, test.expectSeconds) {
+				t.Errorf("Unexpect timeout seconds: %v, expect: %v", seconds, test.expectSeconds)
+			}
+		})
+	}
+}
+
+func TestOptionsE(t *testing.T) {
+	opts := ListOptionsBuilder().
+		Clusters("cluster-1", "cluster 2").
+		LabelSelector("app", []string{"nginx"}).
+		LabelSelector("-app", []string{"nginx"}).
+		FieldSelector("status.phase", nil).
+		Offset(-1).
+		Limit(-10).
+		Timeout(-time.Second).
+		OwnerName("nginx")
+
+	_, err := opts.OptionsE()
+	if err == nil {
+		t.Fatal("Expect validate error")
+	}
+
+	var agg utilerrors.Aggregate
+	if !errors.As(err, &agg) {
+		t.Fatalf("Expect aggregate error, got: %T", err)
+	}
+	expectFields := []string{
+		"limit", "offset", "timeout",
+		"labelSelector[-app]", "labelSelector[search.clusterpedia.io/clusters]",
+		"fieldSelector[status.phase]", "owner.groupResource",
+	}
+	if len(agg.Errors()) != len(expectFields) {
+		t.Errorf("Unexpect errors: %v", err)
+	}
+	for i, e := range agg.Errors() {
+		if i < len(expectFields) && !strings.HasPrefix(e.Error(), expectFields[i]+":") {
+			t.Errorf("Unexpect error: %v, expect field: %s", e, expectFields[i])
+		}
+	}
+
+	// the invalid selector is rejected by clusterpedia instead of panicking
+	if ls := opts.Options().LabelSelector; ls != invalidSelector {
+		t.Errorf("Unexpect label selector: %s", ls)
+	}
+
+	opts.Offset(10).Limit(10).Timeout(time.Minute).OwnerGroupResource(schema.GroupResource{Group: "apps", Resource: "deployments"})
+	if err := opts.Validate(); err == nil || len(err.(utilerrors.Aggregate).Errors()) != 3 {
+		t.Errorf("Unexpect errors after fixing limit, offset, timeout and owner: %v", err)
+	}
+
+	if _, err := ListOptionsBuilder().Clusters("cluster-1").OptionsE(); err != nil {
+		t.Errorf("Unexpect error: %v", err)
+	}
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
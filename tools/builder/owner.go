@@ -0,0 +1,94 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"github.com/clusterpedia-io/client-go/constants"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// OwnerQuery searches the resources by their owner.
+//
+// Either UID or Name must be set, GroupResource is required by Name since
+// owners of different resources may have the same name, e.g.
+//
+//	OwnerQuery{Name: "nginx", GroupResource: schema.GroupResource{Group: "apps", Resource: "deployments"}, Seniority: 1}
+//
+// searches the pods of the deployment nginx, Seniority 1 skips the replicasets.
+type OwnerQuery struct {
+	UID           string
+	Name          string
+	GroupResource schema.GroupResource
+
+	// Seniority is the number of the owner levels above the direct owner, 0 means the direct owner.
+	Seniority int
+}
+
+func (opts *listOptions) OwnerGroupResource(gr schema.GroupResource) ListOptionsInterface {
+	if !gr.Empty() {
+		opts.labels[constants.SearchLabelOwnerGR] = []string{gr.String()}
+	}
+	return opts
+}
+
+// Owner replaces all of the owner options with the owner query,
+// invalid combinations are reported by Validate.
+func (opts *listOptions) Owner(owner OwnerQuery) ListOptionsInterface {
+	opts.owner = &owner
+	delete(opts.labels, constants.SearchLabelOwnerUID)
+	delete(opts.labels, constants.SearchLabelOwnerName)
+	delete(opts.labels, constants.SearchLabelOwnerGR)
+	delete(opts.labels, constants.SearchLabelOwnerSeniority)
+
+	return opts.OwnerUID(owner.UID).
+		OwnerName(owner.Name).
+		OwnerGroupResource(owner.GroupResource).
+		OwnerSeniority(owner.Seniority)
+}
+
+func (opts *listOptions) validateOwner() field.ErrorList {
+	var allErrs field.ErrorList
+	path := field.NewPath("owner")
+	if opts.owner != nil {
+		if opts.owner.Seniority < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("seniority"), opts.owner.Seniority, "must not be negative"))
+		}
+		if opts.owner.UID == "" && opts.owner.Name == "" {
+			allErrs = append(allErrs, field.Required(path, "owner uid or owner name is required"))
+		}
+	}
+
+	_, hasUID := opts.labels[constants.SearchLabelOwnerUID]
+	_, hasName := opts.labels[constants.SearchLabelOwnerName]
+	_, hasGR := opts.labels[constants.SearchLabelOwnerGR]
+	_, hasSeniority := opts.labels[constants.SearchLabelOwnerSeniority]
+	if hasUID && hasName {
+		allErrs = append(allErrs, field.Forbidden(path.Child("name"), "owner uid and owner name can't be used together"))
+	}
+	if hasName && !hasGR {
+		allErrs = append(allErrs, field.Required(path.Child("groupResource"), "required by owner name"))
+	}
+	if hasGR && !hasName {
+		allErrs = append(allErrs, field.Forbidden(path.Child("groupResource"), "can only be used with owner name"))
+	}
+	if hasSeniority && !hasUID && !hasName {
+		allErrs = append(allErrs, field.Forbidden(path.Child("seniority"), "requires owner uid or owner name"))
+	}
+	return allErrs
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var deployments = schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+func TestOwner(t *testing.T) {
+	testCase := []struct {
+		name                string
+		opts                ListOptionsInterface
+		expectLabelSelector string
+		expectErr           bool
+	}{
+		{
+			"uid",
+			ListOptionsBuilder().Owner(OwnerQuery{UID: "4d4d5a6c", Seniority: 1}),
+			"search.clusterpedia.io/owner-seniority=1,search.clusterpedia.io/owner-uid=4d4d5a6c",
+			false,
+		},
+		{
+			"name with group resource",
+			ListOptionsBuilder().Clusters("cluster-1").Owner(OwnerQuery{Name: "nginx", GroupResource: deployments, Seniority: 1}),
+			"search.clusterpedia.io/clusters=cluster-1,search.clusterpedia.io/owner-gr=deployments.apps,search.clusterpedia.io/owner-name=nginx,search.clusterpedia.io/owner-seniority=1",
+			false,
+		},
+		{
+			"core group resource",
+			ListOptionsBuilder().Owner(OwnerQuery{Name: "nginx", GroupResource: schema.GroupResource{Resource: "pods"}}),
+			"search.clusterpedia.io/owner-gr=pods,search.clusterpedia.io/owner-name=nginx",
+			false,
+		},
+		{
+			"owner replaces the previous owner options",
+			ListOptionsBuilder().OwnerName("nginx").OwnerSeniority(2).Owner(OwnerQuery{UID: "4d4d5a6c"}),
+			"search.clusterpedia.io/owner-uid=4d4d5a6c",
+			false,
+		},
+		{
+			"existing methods",
+			ListOptionsBuilder().OwnerName("nginx").OwnerGroupResource(deployments),
+			"search.clusterpedia.io/owner-gr=deployments.apps,search.clusterpedia.io/owner-name=nginx",
+			false,
+		},
+		{
+			"name without group resource",
+			ListOptionsBuilder().Owner(OwnerQuery{Name: "nginx"}),
+			"search.clusterpedia.io/owner-name=nginx",
+			true,
+		},
+		{
+			"uid and name",
+			ListOptionsBuilder().Owner(OwnerQuery{UID: "4d4d5a6c", Name: "nginx", GroupResource: deployments}),
+			"search.clusterpedia.io/owner-gr=deployments.apps,search.clusterpedia.io/owner-name=nginx,search.clusterpedia.io/owner-uid=4d4d5a6c",
+			true,
+		},
+		{
+			"group resource without name",
+			ListOptionsBuilder().Owner(OwnerQuery{UID: "4d4d5a6c", GroupResource: deployments}),
+			"search.clusterpedia.io/owner-gr=deployments.apps,search.clusterpedia.io/owner-uid=4d4d5a6c",
+			true,
+		},
+		{
+			"empty owner",
+			ListOptionsBuilder().Owner(OwnerQuery{}),
+			"",
+			true,
+		},
+		{
+			"negative seniority",
+			ListOptionsBuilder().Owner(OwnerQuery{UID: "4d4d5a6c", Seniority: -1}),
+			"search.clusterpedia.io/owner-uid=4d4d5a6c",
+			true,
+		},
+		{
+			"seniority without owner",
+			ListOptionsBuilder().OwnerSeniority(1),
+			"search.clusterpedia.io/owner-seniority=1",
+			true,
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.opts.Validate(); (err != nil) != test.expectErr {
+				t.Errorf("Unexpect validate error: %v, expect error: %v", err, test.expectErr)
+			}
+			if ls := test.opts.Options().LabelSelector; ls != test.expectLabelSelector {
+				t.Errorf("Unexpect label selector: %s, expect: %s", ls, test.expectLabelSelector)
+			}
+		})
+	}
+}
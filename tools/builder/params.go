@@ -0,0 +1,203 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// The query parameters of clusterpedia which are not search labels.
+const (
+	ParamOnlyMetadata = "onlyMetadata"
+	ParamGroups       = "groups"
+	ParamResources    = "resources"
+	ParamClusters     = "clusters"
+	ParamWhereSQL     = "whereSQL"
+)
+
+// ParamsSource is the params accepted by the WithParams methods of customclient.ResourceInterface and
+// v1beta1.CollectionResourceInterface, it is implemented by ParamsInterface and Params.
+type ParamsSource interface {
+	// ParamsE returns the params or the error of the invalid params, which fails the request.
+	ParamsE() (map[string]string, error)
+}
+
+// Params is the ParamsSource of the params which are already built, e.g. builder.Params{"clusters": "cluster-1"}.
+type Params map[string]string
+
+// ParamsE returns p as is.
+func (p Params) ParamsE() (map[string]string, error) {
+	return p, nil
+}
+
+// ParamsInterface builds the params of customclient.ResourceInterface and
+// v1beta1.CollectionResourceInterface.Fetch, the builder can be passed to the WithParams methods directly,
+// so that the invalid params fail the requests, e.g.
+//
+//	params := builder.ParamsBuilder().OnlyMetadata().
+//		Resources(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"})
+//	c.PediaClusterV1beta1().CollectionResource().FetchWithParams(ctx, "any", opts, params)
+type ParamsInterface interface {
+	// OnlyMetadata only returns the metadata of the collection resource items.
+	OnlyMetadata() ParamsInterface
+	// Groups adds the groups of the collection resource, the version of a group may be empty.
+	Groups(groups ...schema.GroupVersion) ParamsInterface
+	// Resources adds the resources of the collection resource, the version of a resource may be empty.
+	Resources(resources ...schema.GroupVersionResource) ParamsInterface
+	// Clusters searches the resources in the clusters.
+	Clusters(clusters ...string) ParamsInterface
+	// WhereSQL adds the raw SQL condition supported by the internalstorage of clusterpedia,
+	// it requires AllowWhereSQL, otherwise it is dropped by Params and reported by Validate.
+	WhereSQL(sql string) ParamsInterface
+	// AllowWhereSQL opts into WhereSQL, the SQL is sent to clusterpedia as is,
+	// never build it from untrusted input.
+	AllowWhereSQL() ParamsInterface
+	Validate() error
+	Params() map[string]string
+	ParamsE() (map[string]string, error)
+}
+
+type params struct {
+	onlyMetadata bool
+	groups       []schema.GroupVersion
+	resources    []schema.GroupVersionResource
+	clusters     []string
+
+	whereSQL      *string
+	allowWhereSQL bool
+}
+
+func ParamsBuilder() ParamsInterface {
+	return &params{}
+}
+
+func (p *params) OnlyMetadata() ParamsInterface {
+	p.onlyMetadata = true
+	return p
+}
+
+func (p *params) Groups(groups ...schema.GroupVersion) ParamsInterface {
+	p.groups = append(p.groups, groups...)
+	return p
+}
+
+func (p *params) Resources(resources ...schema.GroupVersionResource) ParamsInterface {
+	p.resources = append(p.resources, resources...)
+	return p
+}
+
+func (p *params) Clusters(clusters ...string) ParamsInterface {
+	p.clusters = append(p.clusters, clusters...)
+	return p
+}
+
+func (p *params) WhereSQL(sql string) ParamsInterface {
+	p.whereSQL = &sql
+	return p
+}
+
+func (p *params) AllowWhereSQL() ParamsInterface {
+	p.allowWhereSQL = true
+	return p
+}
+
+// Validate checks the params, all of the problems are aggregated into the returned error.
+func (p *params) Validate() error {
+	var allErrs field.ErrorList
+
+	path := field.NewPath(ParamGroups)
+	for i, gv := range p.groups {
+		if strings.Contains(gv.Group, "/") || strings.Contains(gv.Version, "/") {
+			allErrs = append(allErrs, field.Invalid(path.Index(i), gv.String(), "must not contain '/'"))
+		}
+	}
+	path = field.NewPath(ParamResources)
+	for i, gvr := range p.resources {
+		if gvr.Resource == "" {
+			allErrs = append(allErrs, field.Required(path.Index(i).Child("resource"), ""))
+		}
+		if strings.Contains(gvr.Group, "/") || strings.Contains(gvr.Version, "/") || strings.Contains(gvr.Resource, "/") {
+			allErrs = append(allErrs, field.Invalid(path.Index(i), gvr.String(), "must not contain '/'"))
+		}
+	}
+	path = field.NewPath(ParamClusters)
+	for i, cluster := range p.clusters {
+		if cluster == "" || strings.Contains(cluster, ",") {
+			allErrs = append(allErrs, field.Invalid(path.Index(i), cluster, "must be a non-empty cluster name without ','"))
+		}
+	}
+
+	if p.whereSQL != nil {
+		path = field.NewPath(ParamWhereSQL)
+		if !p.allowWhereSQL {
+			allErrs = append(allErrs, field.Forbidden(path, "whereSQL requires AllowWhereSQL"))
+		}
+		if strings.TrimSpace(*p.whereSQL) == "" {
+			allErrs = append(allErrs, field.Required(path, ""))
+		}
+	}
+	return allErrs.ToAggregate()
+}
+
+// Params returns the params, whereSQL is dropped if it isn't allowed.
+func (p *params) Params() map[string]string {
+	params := make(map[string]string)
+	if p.onlyMetadata {
+		params[ParamOnlyMetadata] = strconv.FormatBool(true)
+	}
+	if len(p.groups) > 0 {
+		groups := make([]string, 0, len(p.groups))
+		for _, gv := range p.groups {
+			group := gv.Group
+			if gv.Version != "" {
+				group += "/" + gv.Version
+			}
+			groups = append(groups, group)
+		}
+		params[ParamGroups] = strings.Join(groups, ",")
+	}
+	if len(p.resources) > 0 {
+		resources := make([]string, 0, len(p.resources))
+		for _, gvr := range p.resources {
+			parts := []string{gvr.Group, gvr.Version, gvr.Resource}
+			if gvr.Version == "" {
+				parts = []string{gvr.Group, gvr.Resource}
+			}
+			resources = append(resources, strings.Join(parts, "/"))
+		}
+		params[ParamResources] = strings.Join(resources, ",")
+	}
+	if len(p.clusters) > 0 {
+		params[ParamClusters] = strings.Join(p.clusters, ",")
+	}
+	if p.whereSQL != nil && p.allowWhereSQL {
+		params[ParamWhereSQL] = *p.whereSQL
+	}
+	return params
+}
+
+// ParamsE validates the params and returns the params if they are valid.
+func (p *params) ParamsE() (map[string]string, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p.Params(), nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParams(t *testing.T) {
+	testCase := []struct {
+		name      string
+		params    ParamsInterface
+		expect    map[string]string
+		expectErr bool
+	}{
+		{
+			"empty",
+			ParamsBuilder(),
+			map[string]string{},
+			false,
+		},
+		{
+			"groups and resources",
+			ParamsBuilder().OnlyMetadata().
+				Groups(schema.GroupVersion{Group: "apps"}, schema.GroupVersion{}).
+				Resources(
+					schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+					schema.GroupVersionResource{Group: "apps", Resource: "daemonsets"},
+					schema.GroupVersionResource{Resource: "pods"},
+				),
+			map[string]string{
+				"onlyMetadata": "true",
+				"groups":       "apps,",
+				"resources":    "apps/v1/deployments,apps/daemonsets,/pods",
+			},
+			false,
+		},
+		{
+			"clusters",
+			ParamsBuilder().Clusters("cluster-1").Clusters("cluster-2").Groups(schema.GroupVersion{Group: "apps", Version: "v1"}),
+			map[string]string{"clusters": "cluster-1,cluster-2", "groups": "apps/v1"},
+			false,
+		},
+		{
+			"allowed whereSQL",
+			ParamsBuilder().AllowWhereSQL().WhereSQL("namespace = 'default'"),
+			map[string]string{"whereSQL": "namespace = 'default'"},
+			false,
+		},
+		{
+			"whereSQL without opting into",
+			ParamsBuilder().OnlyMetadata().WhereSQL("namespace = 'default'"),
+			map[string]string{"onlyMetadata": "true"},
+			true,
+		},
+		{
+			"empty whereSQL",
+			ParamsBuilder().AllowWhereSQL().WhereSQL(" "),
+			map[string]string{"whereSQL": " "},
+			true,
+		},
+		{
+			"invalid resources",
+			ParamsBuilder().Resources(schema.GroupVersionResource{Group: "apps"}, schema.GroupVersionResource{Group: "apps/v1", Resource: "deployments"}),
+			map[string]string{"resources": "apps/,apps/v1/deployments"},
+			true,
+		},
+		{
+			"invalid clusters",
+			ParamsBuilder().Clusters("a,b"),
+			map[string]string{"clusters": "a,b"},
+			true,
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			if params := test.params.Params(); !reflect.DeepEqual(params, test.expect) {
+				t.Errorf("Unexpect params: %v, expect: %v", params, test.expect)
+			}
+
+			params, err := test.params.ParamsE()
+			if (err != nil) != test.expectErr {
+				t.Errorf("Unexpect error: %v, expect error: %v", err, test.expectErr)
+			}
+			if err == nil && !reflect.DeepEqual(params, test.expect) {
+				t.Errorf("Unexpect params: %v, expect: %v", params, test.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clusterpedia-io/client-go/constants"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const dateTimeLayout = "2006-01-02 15:04:05"
+
+// ParseTime parses the datetime formats accepted by clusterpedia for since and before:
+// RFC3339, Datetime(2006-01-02 15:04:05), Date(2006-01-02),
+// and unix timestamps in seconds(10 digits) or milliseconds(13 digits).
+func ParseTime(value string) (time.Time, error) {
+	str := strings.TrimSpace(value)
+
+	var err error
+	var t time.Time
+	switch {
+	case len(str) == 0:
+		err = fmt.Errorf("empty datetime")
+	case strings.Contains(str, "T"):
+		t, err = time.Parse(time.RFC3339, str)
+	case strings.Contains(str, " "):
+		t, err = time.Parse(dateTimeLayout, str)
+	case strings.Contains(str, "-"):
+		t, err = time.Parse(time.DateOnly, str)
+	default:
+		var timestamp int64
+		timestamp, err = strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			break
+		}
+
+		switch len(str) {
+		case 10:
+			t = time.Unix(timestamp, 0)
+		case 13:
+			t = time.UnixMilli(timestamp)
+		default:
+			err = fmt.Errorf("only timestamps with 10(as s) or 13(as ms) digits are supported")
+		}
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid datetime %q, a valid datetime format: RFC3339, Datetime(%s), Date(%s), Unix Timestamp: %w",
+			value, dateTimeLayout, time.DateOnly, err)
+	}
+	return t, nil
+}
+
+// formatTime formats t as a valid label value that clusterpedia can parse back,
+// RFC3339 can't be used since ':' is not allowed in label values.
+//
+// The times other than midnight are formatted as the unix milliseconds padded to 13 digits,
+// so that the times before 2001-09-09 are parsed as milliseconds too, the sub-millisecond
+// precision is truncated, Validate checks the times parsed from the formatted values.
+func formatTime(t time.Time) string {
+	t = t.UTC()
+	if t.Equal(t.Truncate(24 * time.Hour)) {
+		return t.Format(time.DateOnly)
+	}
+	return fmt.Sprintf("%013d", t.UnixMilli())
+}
+
+// validateTimeRange validates the since and before parsed from the label values,
+// which are sent to clusterpedia, instead of the times set by Since and Before.
+func (opts *listOptions) validateTimeRange() field.ErrorList {
+	var allErrs field.ErrorList
+	parse := func(label string, name string, set time.Time) time.Time {
+		if set.IsZero() {
+			return time.Time{}
+		}
+		values := opts.labels[label]
+		if len(values) != 1 {
+			return time.Time{}
+		}
+		t, err := ParseTime(values[0])
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath(name), set.Format(time.RFC3339Nano), err.Error()))
+		}
+		return t
+	}
+	since := parse(constants.SearchLabelSince, "since", opts.since)
+	before := parse(constants.SearchLabelBefore, "before", opts.before)
+
+	if !since.IsZero() && !before.IsZero() && !since.Before(before) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("since"), opts.since.Format(time.RFC3339Nano),
+			fmt.Sprintf("must be before before(%s) in milliseconds", opts.before.Format(time.RFC3339Nano))))
+	}
+	return allErrs
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/clusterpedia-io/client-go/constants"
+)
+
+func TestParseTime(t *testing.T) {
+	testCase := []struct {
+		value     string
+		expect    time.Time
+		expectErr bool
+	}{
+		{"2023-10-26T08:23:06Z", time.Date(2023, 10, 26, 8, 23, 6, 0, time.UTC), false},
+		{"2023-10-26T16:23:06+08:00", time.Date(2023, 10, 26, 8, 23, 6, 0, time.UTC), false},
+		{"2023-10-26 08:23:06", time.Date(2023, 10, 26, 8, 23, 6, 0, time.UTC), false},
+		{"2023-10-26", time.Date(2023, 10, 26, 0, 0, 0, 0, time.UTC), false},
+		{" 2023-10-26 ", time.Date(2023, 10, 26, 0, 0, 0, 0, time.UTC), false},
+		{"1698308586", time.Date(2023, 10, 26, 8, 23, 6, 0, time.UTC), false},
+		{"1698308586123", time.Date(2023, 10, 26, 8, 23, 6, 123e6, time.UTC), false},
+		{"0915152400000", time.Date(1999, 1, 1, 1, 0, 0, 0, time.UTC), false},
+		{"", time.Time{}, true},
+		{"169830858", time.Time{}, true},
+		{"2023/10/26", time.Time{}, true},
+		{"2023-13-26", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := ParseTime(test.value)
+			if test.expectErr {
+				if err == nil {
+					t.Errorf("Expect error, got: %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpect error: %v", err)
+			}
+			if !got.Equal(test.expect) {
+				t.Errorf("Unexpect time: %v, expect: %v", got, test.expect)
+			}
+		})
+	}
+}
+
+func TestSinceBefore(t *testing.T) {
+	date := time.Date(2023, 10, 26, 0, 0, 0, 0, time.UTC)
+	datetime := time.Date(2023, 10, 26, 8, 23, 6, 0, time.UTC)
+
+	testCase := []struct {
+		name                string
+		opts                ListOptionsInterface
+		expectLabelSelector string
+		expectErr           bool
+	}{
+		{
+			"date",
+			ListOptionsBuilder().Since(date),
+			"search.clusterpedia.io/since=2023-10-26",
+			false,
+		},
+		{
+			"unix",
+			ListOptionsBuilder().Before(datetime),
+			"search.clusterpedia.io/before=1698308586000",
+			false,
+		},
+		{
+			"unix milli",
+			ListOptionsBuilder().Before(datetime.Add(123 * time.Millisecond)),
+			"search.clusterpedia.io/before=1698308586123",
+			false,
+		},
+		{
+			"other location",
+			ListOptionsBuilder().Since(date.In(time.FixedZone("UTC+8", 8*60*60))),
+			"search.clusterpedia.io/since=2023-10-26",
+			false,
+		},
+		{
+			"range",
+			ListOptionsBuilder().Since(date).Before(datetime),
+			"search.clusterpedia.io/before=1698308586000,search.clusterpedia.io/since=2023-10-26",
+			false,
+		},
+		{
+			"zero",
+			ListOptionsBuilder().Since(time.Time{}).Before(time.Time{}),
+			"",
+			false,
+		},
+		{
+			"since after before",
+			ListOptionsBuilder().Since(datetime).Before(date),
+			"search.clusterpedia.io/before=2023-10-26,search.clusterpedia.io/since=1698308586000",
+			true,
+		},
+		{
+			"before 2001-09-09",
+			ListOptionsBuilder().Since(time.Date(1999, 1, 1, 1, 0, 0, 0, time.UTC)),
+			"search.clusterpedia.io/since=0915152400000",
+			false,
+		},
+		{
+			"empty range in milliseconds",
+			ListOptionsBuilder().Since(date).Before(date.Add(500 * time.Nanosecond)),
+			"search.clusterpedia.io/before=1698278400000,search.clusterpedia.io/since=2023-10-26",
+			true,
+		},
+		{
+			"before 1970",
+			ListOptionsBuilder().Since(time.Date(1969, 1, 1, 1, 0, 0, 0, time.UTC)),
+			invalidSelector,
+			true,
+		},
+		{
+			"since equal before",
+			ListOptionsBuilder().Since(date).Before(date),
+			"search.clusterpedia.io/before=2023-10-26,search.clusterpedia.io/since=2023-10-26",
+			true,
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.opts.Validate(); (err != nil) != test.expectErr {
+				t.Errorf("Unexpect validate error: %v, expect error: %v", err, test.expectErr)
+			}
+			if ls := test.opts.Options().LabelSelector; ls != test.expectLabelSelector {
+				t.Errorf("Unexpect label selector: %s, expect: %s", ls, test.expectLabelSelector)
+			}
+			if test.expectErr {
+				return
+			}
+			// the formatted times are parsed back by clusterpedia
+			for _, label := range []string{constants.SearchLabelSince, constants.SearchLabelBefore} {
+				for _, value := range test.opts.LabelValues(label) {
+					if _, err := ParseTime(value); err != nil {
+						t.Errorf("Unexpect error of %s: %v", label, err)
+					}
+				}
+			}
+		})
+	}
+}
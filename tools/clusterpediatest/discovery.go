@@ -0,0 +1,148 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterpediatest
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+// shortNames are the short names of the built-in resources served by the discovery.
+var shortNames = map[schema.GroupResource][]string{
+	{Resource: "configmaps"}:                  {"cm"},
+	{Resource: "namespaces"}:                  {"ns"},
+	{Resource: "nodes"}:                       {"no"},
+	{Resource: "persistentvolumeclaims"}:      {"pvc"},
+	{Resource: "persistentvolumes"}:           {"pv"},
+	{Resource: "pods"}:                        {"po"},
+	{Resource: "services"}:                    {"svc"},
+	{Group: "apps", Resource: "daemonsets"}:   {"ds"},
+	{Group: "apps", Resource: "deployments"}:  {"deploy"},
+	{Group: "apps", Resource: "replicasets"}:  {"rs"},
+	{Group: "apps", Resource: "statefulsets"}: {"sts"},
+}
+
+// serveDiscovery serves the discovery of the resources synced from the cluster, or from all of the clusters
+// if cluster is empty, the resources without any objects are not synced.
+// A resource is cluster-scoped if none of its objects has a namespace.
+func (s *Server) serveDiscovery(w http.ResponseWriter, path string) bool {
+	var cluster string
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) >= 2 && segments[0] == "clusters" {
+		cluster, segments = segments[1], segments[2:]
+	}
+
+	switch {
+	case len(segments) == 1 && segments[0] == "api":
+		writeJSON(w, http.StatusOK, &metav1.APIVersions{
+			TypeMeta: metav1.TypeMeta{Kind: "APIVersions"},
+			Versions: []string{"v1"},
+		})
+	case len(segments) == 1 && segments[0] == "apis":
+		writeJSON(w, http.StatusOK, apiGroupList(s.discoveryResources(cluster)))
+	case len(segments) == 2 && segments[0] == "api":
+		gv := schema.GroupVersion{Version: segments[1]}
+		writeJSON(w, http.StatusOK, apiResourceList(gv, s.discoveryResources(cluster)))
+	case len(segments) == 3 && segments[0] == "apis":
+		gv := schema.GroupVersion{Group: segments[1], Version: segments[2]}
+		writeJSON(w, http.StatusOK, apiResourceList(gv, s.discoveryResources(cluster)))
+	default:
+		return false
+	}
+	return true
+}
+
+func (s *Server) discoveryResources(cluster string) map[schema.GroupVersion]map[string]*metav1.APIResource {
+	resources := make(map[schema.GroupVersion]map[string]*metav1.APIResource)
+	for _, obj := range s.objects {
+		if cluster != "" && obj.cluster != cluster {
+			continue
+		}
+		gv := obj.gvr.GroupVersion()
+		if resources[gv] == nil {
+			resources[gv] = make(map[string]*metav1.APIResource)
+		}
+		resource := resources[gv][obj.gvr.Resource]
+		if resource == nil {
+			kind := s.kinds[obj.gvr]
+			resource = &metav1.APIResource{
+				Name:         obj.gvr.Resource,
+				SingularName: strings.ToLower(kind),
+				Kind:         kind,
+				Verbs:        metav1.Verbs{"get", "list"},
+				ShortNames:   shortNames[obj.gvr.GroupResource()],
+			}
+			resources[gv][obj.gvr.Resource] = resource
+		}
+		resource.Namespaced = resource.Namespaced || obj.obj.GetNamespace() != ""
+	}
+	return resources
+}
+
+func apiGroupList(resources map[schema.GroupVersion]map[string]*metav1.APIResource) *metav1.APIGroupList {
+	versions := make(map[string]sets.Set[string])
+	for gv := range resources {
+		if gv.Group == "" {
+			continue
+		}
+		if versions[gv.Group] == nil {
+			versions[gv.Group] = sets.New[string]()
+		}
+		versions[gv.Group].Insert(gv.Version)
+	}
+
+	list := &metav1.APIGroupList{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "APIGroupList"}}
+	for _, group := range sets.List(sets.KeySet(versions)) {
+		// the highest version is preferred
+		groupVersions := sets.List(versions[group])
+		sort.Slice(groupVersions, func(i, j int) bool {
+			return version.CompareKubeAwareVersionStrings(groupVersions[i], groupVersions[j]) > 0
+		})
+
+		apiGroup := metav1.APIGroup{Name: group}
+		for _, v := range groupVersions {
+			apiGroup.Versions = append(apiGroup.Versions, metav1.GroupVersionForDiscovery{
+				GroupVersion: schema.GroupVersion{Group: group, Version: v}.String(),
+				Version:      v,
+			})
+		}
+		apiGroup.PreferredVersion = apiGroup.Versions[0]
+		list.Groups = append(list.Groups, apiGroup)
+	}
+	return list
+}
+
+func apiResourceList(gv schema.GroupVersion, resources map[schema.GroupVersion]map[string]*metav1.APIResource) *metav1.APIResourceList {
+	list := &metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{APIVersion: "v1", Kind: "APIResourceList"},
+		GroupVersion: gv.String(),
+		APIResources: []metav1.APIResource{},
+	}
+	for _, resource := range resources[gv] {
+		list.APIResources = append(list.APIResources, *resource)
+	}
+	sort.Slice(list.APIResources, func(i, j int) bool {
+		return list.APIResources[i].Name < list.APIResources[j].Name
+	})
+	return list
+}
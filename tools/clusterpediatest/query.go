@@ -0,0 +1,311 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterpediatest
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clusterpedia-io/client-go/constants"
+	"github.com/clusterpedia-io/client-go/tools/builder"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+type orderBy struct {
+	field string
+	desc  bool
+}
+
+// query is the search conditions parsed from the request query and label selector.
+type query struct {
+	watch bool
+
+	clusters   []string
+	namespaces []string
+	names      []string
+	fuzzyNames []string
+	orderBy    []orderBy
+
+	since  time.Time
+	before time.Time
+
+	limit              int64
+	offset             int64
+	withRemainingCount bool
+	onlyMetadata       bool
+
+	groups    []string
+	resources []string
+
+	selector labels.Selector
+}
+
+func parseQuery(values url.Values) (*query, error) {
+	q := &query{
+		watch:        values.Get("watch") == "true" || values.Get("watch") == "1",
+		clusters:     splitValues(values.Get("clusters")),
+		namespaces:   splitValues(values.Get("namespaces")),
+		names:        splitValues(values.Get("names")),
+		onlyMetadata: values.Get("onlyMetadata") == "true",
+		selector:     labels.Everything(),
+	}
+	if v, ok := values["groups"]; ok {
+		q.groups = strings.Split(v[0], ",")
+	}
+	if v, ok := values["resources"]; ok {
+		q.resources = strings.Split(v[0], ",")
+	}
+	for _, o := range splitValues(values.Get("orderby")) {
+		field, desc := strings.CutSuffix(o, " desc")
+		q.orderBy = append(q.orderBy, orderBy{field: strings.TrimSpace(field), desc: desc})
+	}
+
+	var err error
+	if limit := values.Get("limit"); limit != "" {
+		if q.limit, err = strconv.ParseInt(limit, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid limit %q: %w", limit, err)
+		}
+	}
+	if offset := values.Get("continue"); offset != "" {
+		if q.offset, err = strconv.ParseInt(offset, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid offset %q: %w", offset, err)
+		}
+	}
+
+	selector, err := labels.Parse(values.Get("labelSelector"))
+	if err != nil {
+		return nil, err
+	}
+	requirements, _ := selector.Requirements()
+
+	var userRequirements []labels.Requirement
+	for _, r := range requirements {
+		values := r.Values().List()
+		switch r.Key() {
+		case constants.SearchLabelClusters:
+			q.clusters = append(q.clusters, values...)
+		case constants.SearchLabelNamespaces:
+			q.namespaces = append(q.namespaces, values...)
+		case constants.SearchLabelNames:
+			q.names = append(q.names, values...)
+		case constants.SearchLabelFuzzyName:
+			q.fuzzyNames = append(q.fuzzyNames, values...)
+		case constants.SearchLabelOrderBy:
+			for _, o := range values {
+				field, desc := strings.CutSuffix(o, constants.OrderByDesc)
+				q.orderBy = append(q.orderBy, orderBy{field: field, desc: desc})
+			}
+		case constants.SearchLabelWithRemainingCount:
+			q.withRemainingCount = len(values) == 1 && values[0] == "true"
+		case constants.SearchLabelWithContinue:
+		case constants.SearchLabelLimit:
+			if q.limit == 0 && len(values) == 1 {
+				if q.limit, err = strconv.ParseInt(values[0], 10, 64); err != nil {
+					return nil, fmt.Errorf("invalid %s %q: %w", r.Key(), values[0], err)
+				}
+			}
+		case constants.SearchLabelOffset:
+			if q.offset == 0 && len(values) == 1 {
+				if q.offset, err = strconv.ParseInt(values[0], 10, 64); err != nil {
+					return nil, fmt.Errorf("invalid %s %q: %w", r.Key(), values[0], err)
+				}
+			}
+		case constants.SearchLabelSince, constants.SearchLabelBefore:
+			if len(values) != 1 {
+				return nil, fmt.Errorf("invalid %s: only one value is allowed", r.Key())
+			}
+			t, err := builder.ParseTime(values[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", r.Key(), err)
+			}
+			if r.Key() == constants.SearchLabelSince {
+				q.since = t
+			} else {
+				q.before = t
+			}
+		default:
+			if strings.Contains(r.Key(), "clusterpedia.io/") {
+				return nil, fmt.Errorf("unsupported search label: %s", r.Key())
+			}
+			userRequirements = append(userRequirements, r)
+		}
+	}
+	if q.offset < 0 {
+		return nil, fmt.Errorf("invalid offset %d: must not be negative", q.offset)
+	}
+
+	q.selector = labels.NewSelector().Add(userRequirements...)
+	return q, nil
+}
+
+func splitValues(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func (q *query) matches(obj *object) bool {
+	if len(q.clusters) > 0 && !sets.New(q.clusters...).Has(obj.cluster) {
+		return false
+	}
+	if len(q.namespaces) > 0 && !sets.New(q.namespaces...).Has(obj.obj.GetNamespace()) {
+		return false
+	}
+	if len(q.names) > 0 && !sets.New(q.names...).Has(obj.obj.GetName()) {
+		return false
+	}
+	for _, name := range q.fuzzyNames {
+		if !strings.Contains(obj.obj.GetName(), name) {
+			return false
+		}
+	}
+
+	created := obj.obj.GetCreationTimestamp().Time
+	if !q.since.IsZero() && created.Before(q.since) {
+		return false
+	}
+	if !q.before.IsZero() && !created.Before(q.before) {
+		return false
+	}
+	return q.selector.Matches(labels.Set(obj.obj.GetLabels()))
+}
+
+// matchesResourceType evaluates the groups and resources parameters of collection resources.
+func (q *query) matchesResourceType(gvr schema.GroupVersionResource) bool {
+	if len(q.groups) == 0 && len(q.resources) == 0 {
+		return true
+	}
+	for _, group := range q.groups {
+		g, version, hasVersion := strings.Cut(group, "/")
+		if g == gvr.Group && (!hasVersion || version == gvr.Version) {
+			return true
+		}
+	}
+	for _, resource := range q.resources {
+		parts := strings.Split(resource, "/")
+		switch len(parts) {
+		case 2:
+			if parts[0] == gvr.Group && parts[1] == gvr.Resource {
+				return true
+			}
+		case 3:
+			if parts[0] == gvr.Group && parts[1] == gvr.Version && parts[2] == gvr.Resource {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (q *query) sort(objs []*object) error {
+	for _, o := range q.orderBy {
+		switch o.field {
+		case "cluster", "namespace", "name", "created_at", "resource_version":
+		default:
+			return fmt.Errorf("unsupported orderby field: %s", o.field)
+		}
+	}
+
+	sort.SliceStable(objs, func(i, j int) bool {
+		for _, o := range q.orderBy {
+			c := compare(o.field, objs[i], objs[j])
+			if c == 0 {
+				continue
+			}
+			if o.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return nil
+}
+
+func compare(field string, a, b *object) int {
+	switch field {
+	case "cluster":
+		return strings.Compare(a.cluster, b.cluster)
+	case "namespace":
+		return strings.Compare(a.obj.GetNamespace(), b.obj.GetNamespace())
+	case "name":
+		return strings.Compare(a.obj.GetName(), b.obj.GetName())
+	case "created_at":
+		ta, tb := a.obj.GetCreationTimestamp(), b.obj.GetCreationTimestamp()
+		switch {
+		case ta.Before(&tb):
+			return -1
+		case tb.Before(&ta):
+			return 1
+		}
+	case "resource_version":
+		ra, _ := strconv.ParseUint(a.obj.GetResourceVersion(), 10, 64)
+		rb, _ := strconv.ParseUint(b.obj.GetResourceVersion(), 10, 64)
+		switch {
+		case ra < rb:
+			return -1
+		case ra > rb:
+			return 1
+		}
+	}
+	return 0
+}
+
+// paginate returns the page of objs, a continue token and the remaining item count.
+// Like clusterpedia, the continue token is the offset of the next page.
+func (q *query) paginate(objs []*object) ([]*object, string, *int64) {
+	total := int64(len(objs))
+	start := q.offset
+	if start > total {
+		start = total
+	}
+	end := total
+	if q.limit > 0 && start+q.limit < total {
+		end = start + q.limit
+	}
+
+	var continueToken string
+	if end < total {
+		continueToken = strconv.FormatInt(end, 10)
+	}
+	var remaining *int64
+	if q.withRemainingCount {
+		count := total - end
+		remaining = &count
+	}
+	return objs[start:end], continueToken, remaining
+}
+
+func (q *query) output(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	if !q.onlyMetadata {
+		return obj
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": obj.GetAPIVersion(),
+		"kind":       obj.GetKind(),
+		"metadata":   obj.Object["metadata"],
+	}}
+}
@@ -0,0 +1,400 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clusterpediatest provides an in-process fake clusterpedia apiserver for tests.
+package clusterpediatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	clusterpediav1beta1 "github.com/clusterpedia-io/api/clusterpedia/v1beta1"
+	"github.com/clusterpedia-io/client-go/constants"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/rest"
+)
+
+const collectionResourcePath = constants.ClusterPediaOriginAPIPath + "/collectionresources"
+
+var collectionResourceKind = clusterpediav1beta1.SchemeGroupVersion.WithKind("CollectionResource")
+
+// Server is a fake clusterpedia apiserver, it serves the resources and collection resources
+// loaded from fixtures and evaluates the search labels of the requests.
+//
+// Served paths:
+//
+//	/apis/clusterpedia.io/v1beta1/resources/{api,apis}/...
+//	/apis/clusterpedia.io/v1beta1/resources/clusters/<cluster>/{api,apis}/...
+//	/apis/clusterpedia.io/v1beta1/collectionresources[/<name>]
+//
+// The discovery of the resources paths only contains the resources of the loaded objects.
+//
+// Watch is not supported, watch requests get a 405 response.
+type Server struct {
+	*httptest.Server
+
+	lock        sync.RWMutex
+	objects     []*object
+	kinds       map[schema.GroupVersionResource]string
+	collections []clusterpediav1beta1.CollectionResource
+}
+
+type object struct {
+	cluster string
+	gvr     schema.GroupVersionResource
+	obj     *unstructured.Unstructured
+}
+
+// NewServer starts a fake clusterpedia apiserver without any objects, the caller should call Close when finished.
+func NewServer() *Server {
+	s := &Server{kinds: make(map[schema.GroupVersionResource]string)}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// NewTestServer starts a fake clusterpedia apiserver with the YAML fixtures,
+// the server is closed when the test finishes.
+func NewTestServer(tb testing.TB, fixtures ...string) *Server {
+	tb.Helper()
+
+	s := NewServer()
+	tb.Cleanup(s.Close)
+	for _, fixture := range fixtures {
+		if err := s.LoadFile(fixture); err != nil {
+			tb.Fatalf("failed to load fixture %s: %v", fixture, err)
+		}
+	}
+	return s
+}
+
+// RESTConfig returns the config of the clients connecting to the server.
+func (s *Server) RESTConfig() *rest.Config {
+	return &rest.Config{Host: s.URL}
+}
+
+// LoadFile loads the YAML or JSON fixture file, see Load.
+func (s *Server) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return s.Load(bytes.NewReader(data))
+}
+
+// Load loads the objects of a multi-document YAML or JSON stream.
+// The cluster of a resource is specified by the shadow.clusterpedia.io/cluster-name annotation,
+// objects of kind clusterpedia.io/v1beta1 CollectionResource are added as collection resources.
+func (s *Server) Load(r io.Reader) error {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if len(bytes.TrimSpace(raw)) == 0 || string(raw) == "null" {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw); err != nil {
+			return err
+		}
+		if obj.GroupVersionKind() == collectionResourceKind {
+			collection := clusterpediav1beta1.CollectionResource{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &collection); err != nil {
+				return err
+			}
+			s.AddCollectionResource(collection)
+			continue
+		}
+
+		cluster := obj.GetAnnotations()[constants.ShadowAnnotationClusterName]
+		if cluster == "" {
+			return fmt.Errorf("%s %s has no %s annotation", obj.GetKind(), obj.GetName(), constants.ShadowAnnotationClusterName)
+		}
+		if err := s.AddObjects(cluster, obj); err != nil {
+			return err
+		}
+	}
+}
+
+// AddObjects adds the objects of the cluster, the kind of objects must be set.
+func (s *Server) AddObjects(cluster string, objs ...runtime.Object) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, obj := range objs {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj.DeepCopyObject())
+		if err != nil {
+			return err
+		}
+		u := &unstructured.Unstructured{Object: content}
+		gvk := u.GroupVersionKind()
+		if gvk.Kind == "" || gvk.Version == "" {
+			return fmt.Errorf("apiVersion and kind of %s are required", u.GetName())
+		}
+
+		annotations := u.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[constants.ShadowAnnotationClusterName] = cluster
+		u.SetAnnotations(annotations)
+
+		gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+		s.kinds[gvr] = gvk.Kind
+		s.objects = append(s.objects, &object{cluster: cluster, gvr: gvr, obj: u})
+	}
+	return nil
+}
+
+// AddCollectionResource adds a collection resource, the items of the collection resource are the objects
+// matched by its resource types, a collection resource without resource types, e.g. "any",
+// requires the groups or resources parameters.
+func (s *Server) AddCollectionResource(collection clusterpediav1beta1.CollectionResource) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	collection.TypeMeta = metav1.TypeMeta{APIVersion: collectionResourceKind.GroupVersion().String(), Kind: collectionResourceKind.Kind}
+	collection.Items = nil
+	s.collections = append(s.collections, collection)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, apierrors.NewMethodNotSupported(schema.GroupResource{}, r.Method))
+		return
+	}
+
+	q, err := parseQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, apierrors.NewBadRequest(err.Error()))
+		return
+	}
+	if q.watch {
+		writeError(w, apierrors.NewMethodNotSupported(schema.GroupResource{}, "watch"))
+		return
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	path := r.URL.Path
+	switch {
+	case path == collectionResourcePath:
+		s.listCollectionResources(w)
+	case strings.HasPrefix(path, collectionResourcePath+"/"):
+		s.fetchCollectionResource(w, strings.TrimPrefix(path, collectionResourcePath+"/"), q)
+	case strings.HasPrefix(path, constants.ClusterPediaAPIPath+"/"):
+		if !s.serveDiscovery(w, strings.TrimPrefix(path, constants.ClusterPediaAPIPath)) {
+			s.serveResource(w, strings.TrimPrefix(path, constants.ClusterPediaAPIPath), q)
+		}
+	default:
+		writeError(w, apierrors.NewNotFound(schema.GroupResource{}, path))
+	}
+}
+
+type resourceRequest struct {
+	cluster   string
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+}
+
+func parseResourcePath(path string) (*resourceRequest, bool) {
+	req := &resourceRequest{}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) >= 2 && segments[0] == "clusters" {
+		req.cluster, segments = segments[1], segments[2:]
+	}
+
+	switch {
+	case len(segments) >= 3 && segments[0] == "api":
+		req.gvr.Version, segments = segments[1], segments[2:]
+	case len(segments) >= 4 && segments[0] == "apis":
+		req.gvr.Group, req.gvr.Version, segments = segments[1], segments[2], segments[3:]
+	default:
+		return nil, false
+	}
+
+	if len(segments) >= 3 && segments[0] == "namespaces" {
+		req.namespace, segments = segments[1], segments[2:]
+	}
+	switch len(segments) {
+	case 2:
+		req.name = segments[1]
+	case 1:
+	default:
+		return nil, false
+	}
+	req.gvr.Resource = segments[0]
+	return req, true
+}
+
+func (s *Server) serveResource(w http.ResponseWriter, path string, q *query) {
+	req, ok := parseResourcePath(path)
+	if !ok {
+		writeError(w, apierrors.NewNotFound(schema.GroupResource{}, path))
+		return
+	}
+	if req.cluster != "" {
+		q.clusters = []string{req.cluster}
+	}
+	if req.namespace != "" {
+		q.namespaces = []string{req.namespace}
+	}
+	if req.name != "" {
+		q.names = []string{req.name}
+	}
+
+	var matched []*object
+	for _, obj := range s.objects {
+		if obj.gvr == req.gvr && q.matches(obj) {
+			matched = append(matched, obj)
+		}
+	}
+
+	if req.name != "" {
+		if len(matched) == 0 {
+			writeError(w, apierrors.NewNotFound(req.gvr.GroupResource(), req.name))
+			return
+		}
+		writeJSON(w, http.StatusOK, matched[0].obj)
+		return
+	}
+
+	if err := q.sort(matched); err != nil {
+		writeError(w, apierrors.NewBadRequest(err.Error()))
+		return
+	}
+	page, continueToken, remaining := q.paginate(matched)
+
+	kind := s.kinds[req.gvr]
+	if kind == "" {
+		kind = "List"
+	} else {
+		kind += "List"
+	}
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{
+		"apiVersion": req.gvr.GroupVersion().String(),
+		"kind":       kind,
+	}}
+	list.SetResourceVersion("1")
+	list.SetContinue(continueToken)
+	list.SetRemainingItemCount(remaining)
+	for _, obj := range page {
+		list.Items = append(list.Items, *q.output(obj.obj))
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) listCollectionResources(w http.ResponseWriter) {
+	list := &clusterpediav1beta1.CollectionResourceList{
+		TypeMeta: metav1.TypeMeta{APIVersion: collectionResourceKind.GroupVersion().String(), Kind: "CollectionResourceList"},
+		Items:    append([]clusterpediav1beta1.CollectionResource{}, s.collections...),
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) fetchCollectionResource(w http.ResponseWriter, name string, q *query) {
+	var collection *clusterpediav1beta1.CollectionResource
+	for i := range s.collections {
+		if s.collections[i].Name == name {
+			collection = s.collections[i].DeepCopy()
+			break
+		}
+	}
+	if collection == nil {
+		writeError(w, apierrors.NewNotFound(schema.GroupResource{Group: clusterpediav1beta1.SchemeGroupVersion.Group, Resource: "collectionresources"}, name))
+		return
+	}
+	if len(collection.ResourceTypes) == 0 && len(q.groups) == 0 && len(q.resources) == 0 {
+		writeError(w, apierrors.NewBadRequest("groups or resources is required"))
+		return
+	}
+
+	var matched []*object
+	for _, obj := range s.objects {
+		if collectionMatches(collection.ResourceTypes, obj.gvr) && q.matchesResourceType(obj.gvr) && q.matches(obj) {
+			matched = append(matched, obj)
+		}
+	}
+	if err := q.sort(matched); err != nil {
+		writeError(w, apierrors.NewBadRequest(err.Error()))
+		return
+	}
+
+	page, continueToken, remaining := q.paginate(matched)
+	for _, obj := range page {
+		raw, err := q.output(obj.obj).MarshalJSON()
+		if err != nil {
+			writeError(w, apierrors.NewInternalError(err))
+			return
+		}
+		collection.Items = append(collection.Items, runtime.RawExtension{Raw: raw})
+	}
+	collection.Continue = continueToken
+	collection.RemainingItemCount = remaining
+	writeJSON(w, http.StatusOK, collection)
+}
+
+func collectionMatches(types []clusterpediav1beta1.CollectionResourceType, gvr schema.GroupVersionResource) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, rt := range types {
+		if rt.Group == gvr.Group && (rt.Resource == "" || rt.Resource == gvr.Resource) && (rt.Version == "" || rt.Version == gvr.Version) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, err *apierrors.StatusError) {
+	status := err.Status()
+	status.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Status"}
+	writeJSON(w, int(status.Code), &status)
+}
+
+func writeJSON(w http.ResponseWriter, code int, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterpediatest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/clusterpedia-io/client-go/client"
+	"github.com/clusterpedia-io/client-go/clusterpediaclient"
+	"github.com/clusterpedia-io/client-go/constants"
+	"github.com/clusterpedia-io/client-go/customclient"
+	"github.com/clusterpedia-io/client-go/tools/builder"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var deploymentsGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+func TestListResources(t *testing.T) {
+	server := NewTestServer(t, "testdata/resources.yaml")
+	c, err := customclient.NewForConfig(server.RESTConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCase := []struct {
+		name            string
+		namespace       string
+		opts            metav1.ListOptions
+		expect          []string
+		expectContinue  string
+		expectRemaining *int64
+	}{
+		{
+			"all",
+			"",
+			builder.ListOptionsBuilder().Options(),
+			[]string{"cluster-1/nginx", "cluster-2/nginx", "cluster-1/nginx-canary", "cluster-1/coredns"},
+			"", nil,
+		},
+		{
+			"clusters and namespaces",
+			"",
+			builder.ListOptionsBuilder().Clusters("cluster-1").Namespaces("default").Options(),
+			[]string{"cluster-1/nginx", "cluster-1/nginx-canary"},
+			"", nil,
+		},
+		{
+			"namespace path",
+			"kube-system",
+			builder.ListOptionsBuilder().Options(),
+			[]string{"cluster-1/coredns"},
+			"", nil,
+		},
+		{
+			"names",
+			"",
+			builder.ListOptionsBuilder().Names("nginx").Options(),
+			[]string{"cluster-1/nginx", "cluster-2/nginx"},
+			"", nil,
+		},
+		{
+			"fuzzy names",
+			"",
+			builder.ListOptionsBuilder().FuzzyNames("canary").Options(),
+			[]string{"cluster-1/nginx-canary"},
+			"", nil,
+		},
+		{
+			"label selector",
+			"",
+			builder.ListOptionsBuilder().LabelSelector("track", []string{"canary"}).Options(),
+			[]string{"cluster-1/nginx-canary"},
+			"", nil,
+		},
+		{
+			"orderby",
+			"",
+			builder.ListOptionsBuilder().OrderBy("created_at", true).Options(),
+			[]string{"cluster-1/nginx-canary", "cluster-2/nginx", "cluster-1/nginx", "cluster-1/coredns"},
+			"", nil,
+		},
+		{
+			"since and before",
+			"",
+			builder.ListOptionsBuilder().
+				Since(time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)).
+				Before(time.Date(2023, 10, 3, 0, 0, 0, 0, time.UTC)).Options(),
+			[]string{"cluster-1/nginx", "cluster-2/nginx"},
+			"", nil,
+		},
+		{
+			"limit and offset",
+			"",
+			builder.ListOptionsBuilder().OrderBy("name").Offset(1).Limit(2).RemainingCount().Options(),
+			[]string{"cluster-1/nginx", "cluster-2/nginx"},
+			"3", int64Ptr(1),
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			list := &unstructured.UnstructuredList{}
+			if err := c.Resource(deploymentsGVR).Namespace(test.namespace).List(context.TODO(), test.opts, nil, list); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, item := range list.Items {
+				got = append(got, item.GetAnnotations()[constants.ShadowAnnotationClusterName]+"/"+item.GetName())
+			}
+			if !reflect.DeepEqual(got, test.expect) {
+				t.Errorf("Unexpect items: %v, expect: %v", got, test.expect)
+			}
+			if list.GetContinue() != test.expectContinue {
+				t.Errorf("Unexpect continue: %q, expect: %q", list.GetContinue(), test.expectContinue)
+			}
+			if !reflect.DeepEqual(list.GetRemainingItemCount(), test.expectRemaining) {
+				t.Errorf("Unexpect remaining item count: %v, expect: %v", list.GetRemainingItemCount(), test.expectRemaining)
+			}
+		})
+	}
+}
+
+func TestUnsupportedSearchLabel(t *testing.T) {
+	server := NewTestServer(t, "testdata/resources.yaml")
+	c, err := customclient.NewForConfig(server.RESTConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := metav1.ListOptions{LabelSelector: "search.clusterpedia.io/unknown=true"}
+	err = c.Resource(deploymentsGVR).List(context.TODO(), opts, nil, &unstructured.UnstructuredList{})
+	if !apierrors.IsBadRequest(err) {
+		t.Errorf("Expect bad request error, got: %v", err)
+	}
+}
+
+func TestNegativeOffset(t *testing.T) {
+	server := NewTestServer(t, "testdata/resources.yaml")
+	c, err := customclient.NewForConfig(server.RESTConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts metav1.ListOptions
+	}{
+		{"continue", metav1.ListOptions{Continue: "-1", Limit: 1}},
+		{"search label", metav1.ListOptions{LabelSelector: "search.clusterpedia.io/offset=-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.Resource(deploymentsGVR).List(context.TODO(), tt.opts, nil, &unstructured.UnstructuredList{})
+			if !apierrors.IsBadRequest(err) {
+				t.Errorf("Expect bad request error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestClusterPath(t *testing.T) {
+	server := NewTestServer(t, "testdata/resources.yaml")
+	c, err := client.NewClusterForConfig(server.RESTConfig(), "cluster-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deploy, err := c.AppsV1().Deployments("default").Get(context.TODO(), "nginx", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cluster := deploy.Annotations[constants.ShadowAnnotationClusterName]; cluster != "cluster-2" {
+		t.Errorf("Unexpect cluster: %s", cluster)
+	}
+
+	deploys, err := c.AppsV1().Deployments("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deploys.Items) != 1 {
+		t.Errorf("Unexpect deployments in cluster-2: %d", len(deploys.Items))
+	}
+
+	_, err = c.AppsV1().Deployments("default").Get(context.TODO(), "nginx-canary", metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("Expect not found error, got: %v", err)
+	}
+}
+
+func TestFetchCollectionResource(t *testing.T) {
+	server := NewTestServer(t, "testdata/resources.yaml")
+	cc, err := clusterpediaclient.NewForConfig(server.RESTConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collections, err := cc.PediaClusterV1beta1().CollectionResource().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collections.Items) != 2 {
+		t.Errorf("Unexpect collection resources: %d", len(collections.Items))
+	}
+
+	resource, err := cc.PediaClusterV1beta1().CollectionResource().Fetch(context.TODO(), "workloads",
+		builder.ListOptionsBuilder().Namespaces("kube-system").Options(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resource.Items) != 2 {
+		t.Errorf("Unexpect workloads in kube-system: %d", len(resource.Items))
+	}
+
+	resource, err = cc.PediaClusterV1beta1().CollectionResource().FetchWithParams(context.TODO(), "any",
+		builder.ListOptionsBuilder().Namespaces("default").Options(), builder.ParamsBuilder().
+			Resources(schema.GroupVersionResource{Resource: "pods"}).OnlyMetadata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resource.Items) != 1 {
+		t.Fatalf("Unexpect pods in default: %d", len(resource.Items))
+	}
+	pod := &unstructured.Unstructured{}
+	if err := pod.UnmarshalJSON(resource.Items[0].Raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pod.Object["status"]; ok || pod.GetName() != "nginx-6b7f675859-2wqsz" {
+		t.Errorf("Unexpect metadata only pod: %v", pod.Object)
+	}
+
+	_, err = cc.PediaClusterV1beta1().CollectionResource().Fetch(context.TODO(), "unknown", metav1.ListOptions{}, nil)
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("Expect not found error, got: %v", err)
+	}
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
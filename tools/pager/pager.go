@@ -0,0 +1,273 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pager
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/clusterpedia-io/client-go/clusterpediaclient/v1beta1"
+	"github.com/clusterpedia-io/client-go/constants"
+	"github.com/clusterpedia-io/client-go/customclient"
+	"github.com/clusterpedia-io/client-go/tools/builder"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const defaultPageSize = 500
+
+// Page is a single page of a list response.
+type Page[T any] struct {
+	Items              []T
+	Continue           string
+	RemainingItemCount *int64
+}
+
+// PageFunc fetches the page described by opts.
+type PageFunc[T any] func(ctx context.Context, opts metav1.ListOptions) (*Page[T], error)
+
+// Pager iterates over the items of a list query page by page.
+//
+// By default the continue token is treated as clusterpedia's offset, the next
+// offset is computed locally when the server does not return one. If the
+// options were built with WithContinue, the server's continue token is used
+// as is and the iteration stops when it is empty.
+//
+// If the continue token of a page is rejected with 410 Gone, the pager restarts
+// once from the continue token of the options, the items of the previous pages
+// are iterated again and Restarted reports true. The items yielded before the
+// restart are invalid, the callback set by OnRestart is called to drop them.
+type Pager[T any] struct {
+	fn           PageFunc[T]
+	options      metav1.ListOptions
+	pageSize     int64
+	withContinue bool
+
+	items     []T
+	index     int
+	item      T
+	next      string
+	remaining *int64
+	done      bool
+	err       error
+	restarted bool
+	onRestart func() error
+	eachItem  bool
+}
+
+// New returns a Pager that lists with opts, pageSize items at a time.
+// A pageSize <= 0 uses the default page size of 500.
+func New[T any](fn PageFunc[T], opts builder.ListOptionsInterface, pageSize int) *Pager[T] {
+	return NewForOptions(fn, opts.Options(), pageSize)
+}
+
+// NewForOptions is like New, but lists with the already built options.
+func NewForOptions[T any](fn PageFunc[T], options metav1.ListOptions, pageSize int) *Pager[T] {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	return &Pager[T]{
+		fn:           fn,
+		options:      options,
+		pageSize:     int64(pageSize),
+		withContinue: withContinue(options.LabelSelector),
+		next:         options.Continue,
+	}
+}
+
+// Next advances to the next item, fetching a new page if needed. It returns
+// false when there are no more items, an error occurred or ctx is done.
+func (p *Pager[T]) Next(ctx context.Context) bool {
+	if p.err != nil {
+		return false
+	}
+	for p.index >= len(p.items) {
+		if p.done {
+			return false
+		}
+		if err := p.fetch(ctx); err != nil {
+			p.err = err
+			return false
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		p.err = err
+		return false
+	}
+
+	p.item = p.items[p.index]
+	p.index++
+	return true
+}
+
+// Item returns the current item.
+func (p *Pager[T]) Item() T {
+	return p.item
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (p *Pager[T]) Err() error {
+	return p.err
+}
+
+// RemainingItemCount returns the remaining item count of the last fetched page,
+// it is only set when the options were built with RemainingCount.
+func (p *Pager[T]) RemainingItemCount() *int64 {
+	return p.remaining
+}
+
+// Restarted returns whether the pager restarted from the continue token of the options
+// because the continue token of a page was expired, the items before the restart are iterated again.
+func (p *Pager[T]) Restarted() bool {
+	return p.restarted
+}
+
+// OnRestart sets fn to be called when the pager restarts, before the first item of the
+// restarted list is yielded, e.g. to drop the items collected so far.
+// If fn returns an error, the iteration stops with it.
+func (p *Pager[T]) OnRestart(fn func() error) *Pager[T] {
+	p.onRestart = fn
+	return p
+}
+
+// EachItem calls fn for every item until the list is exhausted or fn returns an error.
+//
+// EachItem only restarts when a callback is set by OnRestart, otherwise the items passed to fn
+// before the restart could not be dropped, and the error of the expired continue token is returned.
+func (p *Pager[T]) EachItem(ctx context.Context, fn func(item T) error) error {
+	p.eachItem = true
+	for p.Next(ctx) {
+		if err := fn(p.Item()); err != nil {
+			return err
+		}
+	}
+	return p.Err()
+}
+
+func (p *Pager[T]) fetch(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	opts := p.options
+	opts.Limit = p.pageSize
+	opts.Continue = p.next
+	page, err := p.fn(ctx, opts)
+	if err != nil && p.next != p.options.Continue && !p.restarted && (apierrors.IsResourceExpired(err) || apierrors.IsGone(err)) {
+		if p.onRestart == nil && p.eachItem {
+			return err
+		}
+		p.next, p.restarted = p.options.Continue, true
+		if p.onRestart != nil {
+			if err := p.onRestart(); err != nil {
+				return err
+			}
+		}
+		opts.Continue = p.next
+		page, err = p.fn(ctx, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	p.items, p.index = page.Items, 0
+	p.remaining = page.RemainingItemCount
+
+	if p.withContinue {
+		p.next = page.Continue
+		p.done = len(page.Continue) == 0 || len(page.Items) == 0
+		return nil
+	}
+
+	if len(page.Continue) > 0 {
+		p.next = page.Continue
+	} else {
+		var offset int
+		if len(p.next) > 0 {
+			if offset, err = strconv.Atoi(p.next); err != nil {
+				return fmt.Errorf("invalid offset %q: %w", p.next, err)
+			}
+		}
+		p.next = strconv.Itoa(offset + len(page.Items))
+	}
+	p.done = int64(len(page.Items)) < p.pageSize ||
+		(page.RemainingItemCount != nil && *page.RemainingItemCount <= 0)
+	return nil
+}
+
+func withContinue(labelSelector string) bool {
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return false
+	}
+	value, ok := selector.RequiresExactMatch(constants.SearchLabelWithContinue)
+	return ok && value == strconv.FormatBool(true)
+}
+
+// ForResource returns a PageFunc that lists rc into the list returned by newList,
+// every item of the list must be of type T, e.g. *corev1.Pod for a *corev1.PodList.
+func ForResource[T runtime.Object](rc customclient.ResourceInterface, params map[string]string, newList func() runtime.Object) PageFunc[T] {
+	return func(ctx context.Context, opts metav1.ListOptions) (*Page[T], error) {
+		list := newList()
+		if err := rc.List(ctx, opts, params, list); err != nil {
+			return nil, err
+		}
+
+		listMeta, err := meta.ListAccessor(list)
+		if err != nil {
+			return nil, err
+		}
+		objs, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+
+		items := make([]T, 0, len(objs))
+		for _, obj := range objs {
+			item, ok := obj.(T)
+			if !ok {
+				return nil, fmt.Errorf("unexpected list item type %T", obj)
+			}
+			items = append(items, item)
+		}
+		return &Page[T]{
+			Items:              items,
+			Continue:           listMeta.GetContinue(),
+			RemainingItemCount: listMeta.GetRemainingItemCount(),
+		}, nil
+	}
+}
+
+// ForCollectionResource returns a PageFunc that fetches the collection resource name.
+func ForCollectionResource(c v1beta1.CollectionResourceInterface, name string, params map[string]string) PageFunc[runtime.RawExtension] {
+	return func(ctx context.Context, opts metav1.ListOptions) (*Page[runtime.RawExtension], error) {
+		resource, err := c.Fetch(ctx, name, opts, params)
+		if err != nil {
+			return nil, err
+		}
+		return &Page[runtime.RawExtension]{
+			Items:              resource.Items,
+			Continue:           resource.Continue,
+			RemainingItemCount: resource.RemainingItemCount,
+		}, nil
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pager
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/clusterpedia-io/client-go/tools/builder"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakePages serves items by offset, withContinue controls whether a continue token is returned.
+func fakePages(items []int, withContinue bool, calls *[]metav1.ListOptions) PageFunc[int] {
+	return func(ctx context.Context, opts metav1.ListOptions) (*Page[int], error) {
+		*calls = append(*calls, opts)
+
+		var offset int
+		if opts.Continue != "" {
+			offset, _ = strconv.Atoi(opts.Continue)
+		}
+		end := offset + int(opts.Limit)
+		if end > len(items) {
+			end = len(items)
+		}
+
+		page := &Page[int]{Items: items[offset:end]}
+		if withContinue && end < len(items) {
+			page.Continue = strconv.Itoa(end)
+		}
+		return page, nil
+	}
+}
+
+func TestPager(t *testing.T) {
+	items := []int{0, 1, 2, 3, 4, 5, 6}
+
+	testCase := []struct {
+		name          string
+		opts          builder.ListOptionsInterface
+		withContinue  bool
+		pageSize      int
+		expectItems   []int
+		expectFetches int
+	}{
+		{"offset", builder.ListOptionsBuilder(), false, 3, items, 3},
+		{"offset exact pages", builder.ListOptionsBuilder(), false, 7, items, 2},
+		{"offset start", builder.ListOptionsBuilder().Offset(2), false, 3, items[2:], 2},
+		{"with continue", builder.ListOptionsBuilder().WithContinue(), true, 3, items, 3},
+		{"with continue exact pages", builder.ListOptionsBuilder().WithContinue(), true, 7, items, 1},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			var calls []metav1.ListOptions
+			p := New(fakePages(items, test.withContinue, &calls), test.opts, test.pageSize)
+
+			var got []int
+			if err := p.EachItem(context.TODO(), func(item int) error {
+				got = append(got, item)
+				return nil
+			}); err != nil {
+				t.Fatalf("Unexpect error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.expectItems) {
+				t.Errorf("Unexpect items: %v, expect: %v", got, test.expectItems)
+			}
+			if len(calls) != test.expectFetches {
+				t.Errorf("Unexpect fetches: %d, expect: %d", len(calls), test.expectFetches)
+			}
+			for _, call := range calls {
+				if call.Limit != int64(test.pageSize) {
+					t.Errorf("Unexpect limit: %d, expect: %d", call.Limit, test.pageSize)
+				}
+			}
+		})
+	}
+}
+
+func TestPagerContextCancel(t *testing.T) {
+	var calls []metav1.ListOptions
+	p := New(fakePages([]int{0, 1, 2, 3}, false, &calls), builder.ListOptionsBuilder(), 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if !p.Next(ctx) || p.Item() != 0 {
+		t.Fatalf("Unexpect first item: %v, err: %v", p.Item(), p.Err())
+	}
+	cancel()
+
+	if p.Next(ctx) {
+		t.Fatalf("Unexpect item after cancel: %v", p.Item())
+	}
+	if p.Err() != context.Canceled {
+		t.Errorf("Unexpect error: %v, expect: %v", p.Err(), context.Canceled)
+	}
+	if len(calls) != 1 {
+		t.Errorf("Unexpect fetches: %d, expect: 1", len(calls))
+	}
+}
+
+func TestPagerRestartExpired(t *testing.T) {
+	items := []int{0, 1, 2, 3, 4}
+	testCase := []struct {
+		name            string
+		start           string
+		expirations     int
+		expectItems     []int
+		expectContinues []string
+		noRestart       bool
+		expectRestarts  int
+		expectErr       bool
+	}{
+		{"restart from offset 0", "", 1, []int{0, 1, 2, 3, 4}, []string{"", "2", "4", "", "2", "4"}, false, 1, false},
+		{"restart from the continue of the options", "2", 1, []int{2, 3, 4}, []string{"2", "4", "2", "4"}, false, 1, false},
+		{"restart once", "", 2, []int{0, 1, 2, 3}, []string{"", "2", "4", "", "2", "4"}, false, 1, true},
+		{"no restart without callback", "", 1, []int{0, 1, 2, 3}, []string{"", "2", "4"}, true, 0, true},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			pages := fakePages(items, true, new([]metav1.ListOptions))
+			var continues []string
+			var expired int
+			fn := func(ctx context.Context, opts metav1.ListOptions) (*Page[int], error) {
+				continues = append(continues, opts.Continue)
+				if opts.Continue == "4" && expired < test.expirations {
+					expired++
+					return nil, apierrors.NewResourceExpired("the continue token is expired")
+				}
+				return pages(ctx, opts)
+			}
+
+			options := builder.ListOptionsBuilder().WithContinue().Options()
+			options.Continue = test.start
+			p := NewForOptions(fn, options, 2)
+
+			var got []int
+			var restarts int
+			if !test.noRestart {
+				p.OnRestart(func() error {
+					restarts++
+					got = nil
+					return nil
+				})
+			}
+			err := p.EachItem(context.TODO(), func(item int) error {
+				got = append(got, item)
+				return nil
+			})
+			if p.Restarted() != (test.expectRestarts > 0) {
+				t.Errorf("Unexpect restarted: %v", p.Restarted())
+			}
+			if restarts != test.expectRestarts {
+				t.Errorf("Unexpect restarts: %d, expect: %d", restarts, test.expectRestarts)
+			}
+			if test.expectErr != apierrors.IsResourceExpired(err) {
+				t.Fatalf("Unexpect error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.expectItems) {
+				t.Errorf("Unexpect items: %v, expect: %v", got, test.expectItems)
+			}
+			if !reflect.DeepEqual(continues, test.expectContinues) {
+				t.Errorf("Unexpect continue tokens: %v, expect: %v", continues, test.expectContinues)
+			}
+		})
+	}
+}
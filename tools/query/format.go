@@ -0,0 +1,211 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/clusterpedia-io/client-go/constants"
+	"github.com/clusterpedia-io/client-go/tools/builder"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// conditionOrder is the order of the conditions in the formatted query,
+// labels and fields are always at the end.
+var conditionOrder = []struct {
+	label          string
+	single, plural string
+}{
+	{constants.SearchLabelClusters, "cluster", "clusters"},
+	{constants.SearchLabelNamespaces, "namespace", "namespaces"},
+	{constants.SearchLabelNames, "name", "names"},
+	{constants.SearchLabelOwnerUID, "owner_uid", ""},
+	{constants.SearchLabelOwnerName, "owner_name", ""},
+	{constants.SearchLabelOwnerGR, "owner_gr", ""},
+	{constants.SearchLabelOwnerSeniority, "owner_seniority", ""},
+	{constants.SearchLabelSince, "since", ""},
+	{constants.SearchLabelBefore, "before", ""},
+}
+
+// Format prints the list options as a query, Parse(Format(opts)) builds the same list options.
+// An error is returned if the options contain selectors that can't be expressed by the query,
+// e.g. the '!=' operator.
+func Format(opts metav1.ListOptions) (string, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return "", fmt.Errorf("invalid label selector: %w", err)
+	}
+	requirements, _ := selector.Requirements()
+
+	var (
+		search     = make(map[string][]string)
+		fuzzyNames []string
+		userLabels []string
+		orderBy    []string
+		with       []string
+		limit      = opts.Limit
+		offset     = opts.Continue
+		// seen records the labels and fields, the repeated conditions are rejected by Parse
+		seen = make(map[string]bool)
+	)
+	for _, r := range requirements {
+		values, err := requirementValues(r)
+		if err != nil {
+			return "", err
+		}
+
+		switch r.Key() {
+		case constants.SearchLabelFuzzyName:
+			fuzzyNames = append(fuzzyNames, values...)
+		case constants.SearchLabelOrderBy:
+			for _, v := range values {
+				if field, desc := strings.CutSuffix(v, constants.OrderByDesc); desc {
+					orderBy = append(orderBy, field+" desc")
+				} else {
+					orderBy = append(orderBy, v)
+				}
+			}
+		case constants.SearchLabelWithRemainingCount:
+			if len(values) == 1 && values[0] == "true" {
+				with = append(with, "remaining_count")
+			}
+		case constants.SearchLabelWithContinue:
+			if len(values) == 1 && values[0] == "true" {
+				with = append(with, "continue")
+			}
+		case constants.SearchLabelLimit:
+			if limit == 0 && len(values) == 1 {
+				if limit, err = strconv.ParseInt(values[0], 10, 64); err != nil {
+					return "", fmt.Errorf("invalid %s: %w", r.Key(), err)
+				}
+			}
+		case constants.SearchLabelOffset:
+			if offset == "" && len(values) == 1 {
+				offset = values[0]
+			}
+		default:
+			if strings.Contains(r.Key(), "clusterpedia.io/") && !isConditionLabel(r.Key()) {
+				return "", fmt.Errorf("unsupported search label %q", r.Key())
+			}
+			if isConditionLabel(r.Key()) {
+				search[r.Key()] = append(search[r.Key()], values...)
+				continue
+			}
+			if seen[labelKeyPrefix+r.Key()] {
+				return "", fmt.Errorf("label %s is required more than once", r.Key())
+			}
+			seen[labelKeyPrefix+r.Key()] = true
+			userLabels = append(userLabels, formatCondition(labelKeyPrefix+r.Key(), "", values))
+		}
+	}
+
+	var conditions []string
+	for _, c := range conditionOrder {
+		if values, ok := search[c.label]; ok {
+			conditions = append(conditions, formatCondition(c.single, c.plural, values))
+		}
+		if c.label == constants.SearchLabelNames {
+			for _, name := range fuzzyNames {
+				conditions = append(conditions, "name~"+formatValue(name))
+			}
+		}
+	}
+	conditions = append(conditions, userLabels...)
+
+	// the builder joins the values of a field with the 'in' operator and builds the bracketed field paths,
+	// which are not supported by fields.ParseSelector, so the field selector is parsed by the builder
+	fieldRequirements, err := builder.ParseFieldSelector(opts.FieldSelector)
+	if err != nil {
+		return "", fmt.Errorf("invalid field selector: %w", err)
+	}
+	for _, r := range fieldRequirements {
+		switch r.Operator {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+		default:
+			return "", fmt.Errorf("operator %q of %s is not supported by the query", r.Operator, r.Field)
+		}
+		if seen[fieldKeyPrefix+r.Field] {
+			return "", fmt.Errorf("field %s is required more than once", r.Field)
+		}
+		seen[fieldKeyPrefix+r.Field] = true
+		conditions = append(conditions, formatCondition(fieldKeyPrefix+r.Field, "", r.Values))
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(conditions, " and "))
+	clause := func(s string) {
+		if b.Len() > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(s)
+	}
+	if len(orderBy) > 0 {
+		clause("order by " + strings.Join(orderBy, ", "))
+	}
+	if limit > 0 {
+		clause("limit " + strconv.FormatInt(limit, 10))
+	}
+	if offset != "" {
+		if n, err := strconv.Atoi(offset); err != nil || n < 0 {
+			return "", fmt.Errorf("continue %q is not an offset", offset)
+		}
+		clause("offset " + offset)
+	}
+	if len(with) > 0 {
+		clause("with " + strings.Join(with, ", "))
+	}
+	return b.String(), nil
+}
+
+func isConditionLabel(label string) bool {
+	for _, c := range conditionOrder {
+		if c.label == label {
+			return true
+		}
+	}
+	return false
+}
+
+func requirementValues(r labels.Requirement) ([]string, error) {
+	switch r.Operator() {
+	case selection.Equals, selection.DoubleEquals, selection.In:
+		return r.Values().List(), nil
+	}
+	return nil, fmt.Errorf("operator %q of %s is not supported by the query", r.Operator(), r.Key())
+}
+
+// formatCondition formats the values of key, plural is used as the key for multiple values if it is set.
+func formatCondition(single, plural string, values []string) string {
+	if len(values) == 1 {
+		return single + "=" + formatValue(values[0])
+	}
+
+	key := single
+	if plural != "" {
+		key = plural
+	}
+	formatted := make([]string, 0, len(values))
+	for _, v := range values {
+		formatted = append(formatted, formatValue(v))
+	}
+	return key + " in (" + strings.Join(formatted, ",") + ")"
+}
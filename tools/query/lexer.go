@@ -0,0 +1,197 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	// tokenWord is a bare word, keywords are words as well
+	tokenWord
+	// tokenString is a quoted string, the value is unquoted
+	tokenString
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+	tokenEquals
+	tokenTilde
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of query"
+	case tokenWord:
+		return "word"
+	case tokenString:
+		return "string"
+	case tokenLeftParen:
+		return "'('"
+	case tokenRightParen:
+		return "')'"
+	case tokenComma:
+		return "','"
+	case tokenEquals:
+		return "'='"
+	case tokenTilde:
+		return "'~'"
+	}
+	return "unknown token"
+}
+
+type token struct {
+	kind  tokenKind
+	value string
+	// column is the 1-based column of the first character of the token
+	column int
+}
+
+// is reports whether the token is the keyword, keywords are case insensitive.
+func (t token) is(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.value, keyword)
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenWord:
+		return "'" + t.value + "'"
+	case tokenString:
+		return "string " + quote(t.value)
+	}
+	return t.kind.String()
+}
+
+func isWordChar(r rune) bool {
+	switch {
+	case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("-_./:+*", r)
+}
+
+// lex splits the query into tokens, the last token is always tokenEOF.
+func lex(query string) ([]token, error) {
+	var tokens []token
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r, column := runes[i], i+1
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, value: "(", column: column})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, value: ")", column: column})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, value: ",", column: column})
+			i++
+		case r == '~':
+			tokens = append(tokens, token{kind: tokenTilde, value: "~", column: column})
+			i++
+		case r == '=':
+			// '==' is the same as '='
+			i++
+			if i < len(runes) && runes[i] == '=' {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenEquals, value: "=", column: column})
+		case r == '\'' || r == '"':
+			var b strings.Builder
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					b.WriteRune(runes[i])
+					continue
+				}
+				if runes[i] == r {
+					closed = true
+					i++
+					break
+				}
+				b.WriteRune(runes[i])
+			}
+			if !closed {
+				return nil, &Error{Column: column, Msg: "unterminated string"}
+			}
+			tokens = append(tokens, token{kind: tokenString, value: b.String(), column: column})
+		case isWordChar(r):
+			start := i
+			for i < len(runes) && (isWordChar(runes[i]) || runes[i] == '[') {
+				if runes[i] != '[' {
+					i++
+					continue
+				}
+				// the bracketed segments of the field paths, e.g. spec.containers[0] and
+				// metadata.annotations['app.io/x'], are part of the word, the quoted names are kept as is
+				end, err := skipBracket(runes, i)
+				if err != nil {
+					return nil, err
+				}
+				i = end
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: string(runes[start:i]), column: column})
+		default:
+			return nil, &Error{Column: column, Msg: "unexpected character " + quote(string(r))}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, column: len(runes) + 1}), nil
+}
+
+// skipBracket returns the index after the bracket starting at start, the quoted names in the bracket
+// may contain ']', they are not escaped like the field paths of clusterpedia.
+func skipBracket(runes []rune, start int) (int, error) {
+	var quote rune
+	for i := start + 1; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ']':
+			return i + 1, nil
+		}
+	}
+	return 0, &Error{Column: start + 1, Msg: "unterminated bracket"}
+}
+
+// quote quotes s as a DSL string.
+func quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// formatValue returns v as a bare word if the lexer reads it back as a single word,
+// otherwise v is quoted.
+func formatValue(v string) string {
+	if v == "" || isKeyword(v) {
+		return quote(v)
+	}
+	for _, r := range v {
+		if !isWordChar(r) {
+			return quote(v)
+		}
+	}
+	return v
+}
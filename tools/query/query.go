@@ -0,0 +1,506 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package query implements a small textual query language for clusterpedia searches, e.g.
+//
+//	clusters in (cluster-1,cluster-2) and namespace=default and name~ngin order by created_at desc limit 10
+//
+// A query is a list of conditions joined by 'and', followed by optional clauses:
+//
+//	<key> = <value>
+//	<key> in (<value>, ...)
+//	name ~ <value>                         fuzzy search of names
+//	order by <field> [asc|desc], ...       cluster, namespace, name, created_at, resource_version
+//	limit <n>
+//	offset <n>
+//	with remaining_count, continue
+//
+// The keys are cluster(s), namespace(s), name(s), owner_uid, owner_name, owner_gr, owner_seniority,
+// since, before, the search labels in constants, label.<label key> for the labels of resources
+// and field.<field path> for the field selector, the field path may contain the bracketed segments
+// of clusterpedia, e.g. field.spec.containers[0].image and field.metadata.annotations['app.io/x'].
+// Values containing characters other than
+// letters, digits and "-_./:+*" must be quoted with ' or ", and \ escapes the next character.
+// Keywords are case insensitive.
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/clusterpedia-io/client-go/constants"
+	"github.com/clusterpedia-io/client-go/tools/builder"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Error is a syntax or semantic error of a query.
+type Error struct {
+	// Column is the 1-based column of the query where the error is found
+	Column int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Msg)
+}
+
+const (
+	labelKeyPrefix = "label."
+	fieldKeyPrefix = "field."
+)
+
+var keywords = map[string]bool{
+	"and": true, "in": true, "order": true, "by": true, "asc": true, "desc": true,
+	"limit": true, "offset": true, "with": true,
+}
+
+func isKeyword(s string) bool {
+	return keywords[strings.ToLower(s)]
+}
+
+// keyAliases maps the short keys of the query to the search labels.
+var keyAliases = map[string]string{
+	"cluster":         constants.SearchLabelClusters,
+	"clusters":        constants.SearchLabelClusters,
+	"namespace":       constants.SearchLabelNamespaces,
+	"namespaces":      constants.SearchLabelNamespaces,
+	"name":            constants.SearchLabelNames,
+	"names":           constants.SearchLabelNames,
+	"owner_uid":       constants.SearchLabelOwnerUID,
+	"owner_name":      constants.SearchLabelOwnerName,
+	"owner_gr":        constants.SearchLabelOwnerGR,
+	"owner_seniority": constants.SearchLabelOwnerSeniority,
+	"since":           constants.SearchLabelSince,
+	"before":          constants.SearchLabelBefore,
+}
+
+// clauseLabels are the search labels that are set by clauses instead of conditions.
+var clauseLabels = map[string]string{
+	constants.SearchLabelOrderBy:            "order by",
+	constants.SearchLabelLimit:              "limit",
+	constants.SearchLabelOffset:             "offset",
+	constants.SearchLabelWithRemainingCount: "with remaining_count",
+	constants.SearchLabelWithContinue:       "with continue",
+}
+
+// singleValueLabels can only be set once with a single value.
+var singleValueLabels = map[string]bool{
+	constants.SearchLabelOwnerUID:       true,
+	constants.SearchLabelOwnerName:      true,
+	constants.SearchLabelOwnerGR:        true,
+	constants.SearchLabelOwnerSeniority: true,
+	constants.SearchLabelSince:          true,
+	constants.SearchLabelBefore:         true,
+}
+
+// OrderByFields are the fields supported by the order by clause.
+var OrderByFields = []string{"cluster", "namespace", "name", "created_at", "resource_version"}
+
+// Parse parses the query into a new ListOptionsBuilder.
+func Parse(query string) (builder.ListOptionsInterface, error) {
+	opts := builder.ListOptionsBuilder()
+	if err := ParseInto(query, opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// ParseInto parses the query and adds the conditions to opts,
+// opts may be partially updated if an error is returned.
+func ParseInto(query string, opts builder.ListOptionsInterface) error {
+	tokens, err := lex(query)
+	if err != nil {
+		return err
+	}
+	p := &parser{tokens: tokens, opts: opts, seen: make(map[string]bool)}
+	return p.parse()
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	opts   builder.ListOptionsInterface
+
+	// seen records the keys of the conditions and the clauses which have been parsed
+	seen map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, unexpected(t, kind.String())
+	}
+	return t, nil
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if t := p.next(); !t.is(keyword) {
+		return unexpected(t, "'"+keyword+"'")
+	}
+	return nil
+}
+
+func unexpected(t token, expect string) *Error {
+	return &Error{Column: t.column, Msg: fmt.Sprintf("unexpected %s, expect %s", t, expect)}
+}
+
+func isClause(t token) bool {
+	return t.is("order") || t.is("limit") || t.is("offset") || t.is("with")
+}
+
+func (p *parser) parse() error {
+	if t := p.peek(); t.kind != tokenEOF && !isClause(t) {
+		for {
+			if err := p.parseCondition(); err != nil {
+				return err
+			}
+			if !p.peek().is("and") {
+				break
+			}
+			p.next()
+		}
+	}
+
+	for {
+		t := p.peek()
+		if t.kind == tokenEOF {
+			return nil
+		}
+		if !isClause(t) {
+			return unexpected(t, "'and', 'order by', 'limit', 'offset', 'with' or end of query")
+		}
+
+		clause := strings.ToLower(t.value)
+		if p.seen[clause] {
+			return &Error{Column: t.column, Msg: fmt.Sprintf("duplicate %s clause", clause)}
+		}
+		p.seen[clause] = true
+		p.next()
+
+		var err error
+		switch clause {
+		case "order":
+			err = p.parseOrderBy()
+		case "limit":
+			err = p.parseNumber(func(n int) error {
+				if n <= 0 {
+					return fmt.Errorf("limit must be positive")
+				}
+				p.opts.Limit(n)
+				return nil
+			})
+		case "offset":
+			err = p.parseNumber(func(n int) error {
+				p.opts.Offset(n)
+				return nil
+			})
+		case "with":
+			err = p.parseWith()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// resolveKey returns the search label, label key or field path of the key token.
+func (p *parser) resolveKey(t token) (kind string, key string, err error) {
+	if t.kind != tokenWord || isKeyword(t.value) {
+		return "", "", unexpected(t, "key")
+	}
+
+	switch {
+	case strings.HasPrefix(t.value, labelKeyPrefix):
+		key = strings.TrimPrefix(t.value, labelKeyPrefix)
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return "", "", &Error{Column: t.column, Msg: fmt.Sprintf("invalid label key %q: %s", key, strings.Join(errs, "; "))}
+		}
+		if strings.Contains(key, "clusterpedia.io/") {
+			return "", "", &Error{Column: t.column, Msg: fmt.Sprintf("search label %q must not be used as a label", key)}
+		}
+		return labelKeyPrefix, key, nil
+	case strings.HasPrefix(t.value, fieldKeyPrefix):
+		key = strings.TrimPrefix(t.value, fieldKeyPrefix)
+		if key == "" {
+			return "", "", &Error{Column: t.column, Msg: "empty field path"}
+		}
+		return fieldKeyPrefix, key, nil
+	}
+
+	if label, ok := keyAliases[strings.ToLower(t.value)]; ok {
+		return "", label, nil
+	}
+	switch t.value {
+	case constants.SearchLabelClusters, constants.SearchLabelNamespaces, constants.SearchLabelNames,
+		constants.SearchLabelFuzzyName, constants.SearchLabelOwnerUID, constants.SearchLabelOwnerName,
+		constants.SearchLabelOwnerGR, constants.SearchLabelOwnerSeniority, constants.SearchLabelSince, constants.SearchLabelBefore:
+		return "", t.value, nil
+	}
+	if clause, ok := clauseLabels[t.value]; ok {
+		return "", "", &Error{Column: t.column, Msg: fmt.Sprintf("%s must be set by the '%s' clause", t.value, clause)}
+	}
+	if strings.Contains(t.value, "clusterpedia.io/") {
+		return "", "", &Error{Column: t.column, Msg: fmt.Sprintf("unsupported search label %q", t.value)}
+	}
+	return "", "", &Error{Column: t.column, Msg: fmt.Sprintf("unknown key %q, use %s<key> for labels or %s<path> for fields", t.value, labelKeyPrefix, fieldKeyPrefix)}
+}
+
+func (p *parser) parseCondition() error {
+	keyToken := p.next()
+	kind, key, err := p.resolveKey(keyToken)
+	if err != nil {
+		return err
+	}
+
+	opToken := p.next()
+	var values []token
+	switch {
+	case opToken.kind == tokenEquals:
+		v, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		values = []token{v}
+	case opToken.kind == tokenTilde:
+		if key != constants.SearchLabelNames {
+			return &Error{Column: opToken.column, Msg: "'~' is only supported by name"}
+		}
+		key = constants.SearchLabelFuzzyName
+
+		v, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		values = []token{v}
+	case opToken.is("in"):
+		if singleValueLabels[key] {
+			return &Error{Column: opToken.column, Msg: fmt.Sprintf("%s only accepts a single value", keyToken.value)}
+		}
+		if values, err = p.parseValueList(); err != nil {
+			return err
+		}
+	default:
+		return unexpected(opToken, "'=', '~' or 'in'")
+	}
+
+	// the values of a key are joined by 'in' in the selectors, so the repeated conditions
+	// joined by 'and' would match any of the values, the fuzzy names are matched together
+	if key != constants.SearchLabelFuzzyName {
+		if p.seen[kind+key] {
+			msg := fmt.Sprintf("duplicate condition on %s", keyToken.value)
+			if !singleValueLabels[key] {
+				msg += ", use 'in' to match any of the values"
+			}
+			return &Error{Column: keyToken.column, Msg: msg}
+		}
+		p.seen[kind+key] = true
+	}
+
+	switch kind {
+	case labelKeyPrefix:
+		if err := validateLabelValues(values); err != nil {
+			return err
+		}
+		p.opts.LabelSelector(key, tokenValues(values))
+		return nil
+	case fieldKeyPrefix:
+		p.opts.FieldSelector(key, tokenValues(values))
+		return nil
+	}
+
+	v := values[0]
+	switch key {
+	case constants.SearchLabelSince, constants.SearchLabelBefore:
+		t, err := builder.ParseTime(v.value)
+		if err != nil {
+			return &Error{Column: v.column, Msg: err.Error()}
+		}
+		if key == constants.SearchLabelSince {
+			p.opts.Since(t)
+		} else {
+			p.opts.Before(t)
+		}
+		return nil
+	case constants.SearchLabelOwnerSeniority:
+		n, err := strconv.Atoi(v.value)
+		if err != nil || n <= 0 {
+			return &Error{Column: v.column, Msg: fmt.Sprintf("owner seniority must be a positive integer, got %q", v.value)}
+		}
+		p.opts.OwnerSeniority(n)
+		return nil
+	}
+
+	if err := validateLabelValues(values); err != nil {
+		return err
+	}
+	switch key {
+	case constants.SearchLabelClusters:
+		p.opts.Clusters(tokenValues(values)...)
+	case constants.SearchLabelNamespaces:
+		p.opts.Namespaces(tokenValues(values)...)
+	case constants.SearchLabelNames:
+		p.opts.Names(tokenValues(values)...)
+	case constants.SearchLabelFuzzyName:
+		p.opts.FuzzyNames(tokenValues(values)...)
+	case constants.SearchLabelOwnerUID:
+		p.opts.OwnerUID(v.value)
+	case constants.SearchLabelOwnerName:
+		p.opts.OwnerName(v.value)
+	case constants.SearchLabelOwnerGR:
+		p.opts.OwnerGroupResource(schema.ParseGroupResource(v.value))
+	}
+	return nil
+}
+
+func (p *parser) parseValue() (token, error) {
+	t := p.next()
+	if t.kind != tokenWord && t.kind != tokenString {
+		return t, unexpected(t, "value")
+	}
+	return t, nil
+}
+
+func (p *parser) parseValueList() ([]token, error) {
+	if _, err := p.expect(tokenLeftParen); err != nil {
+		return nil, err
+	}
+
+	var values []token
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		t := p.next()
+		if t.kind == tokenRightParen {
+			return values, nil
+		}
+		if t.kind != tokenComma {
+			return nil, unexpected(t, "',' or ')'")
+		}
+	}
+}
+
+func (p *parser) parseOrderBy() error {
+	if err := p.expectKeyword("by"); err != nil {
+		return err
+	}
+
+	for {
+		t, err := p.expect(tokenWord)
+		if err != nil {
+			return err
+		}
+		if !isOrderByField(t.value) {
+			return &Error{Column: t.column, Msg: fmt.Sprintf("unsupported order by field %q, supported fields: %s", t.value, strings.Join(OrderByFields, ", "))}
+		}
+
+		desc := false
+		if next := p.peek(); next.is("asc") || next.is("desc") {
+			desc = next.is("desc")
+			p.next()
+		}
+		p.opts.OrderBy(t.value, desc)
+
+		if p.peek().kind != tokenComma {
+			return nil
+		}
+		p.next()
+	}
+}
+
+func (p *parser) parseNumber(apply func(n int) error) error {
+	t := p.next()
+	if t.kind != tokenWord {
+		return unexpected(t, "number")
+	}
+	n, err := strconv.Atoi(t.value)
+	if err == nil && n < 0 {
+		err = fmt.Errorf("must not be negative")
+	}
+	if err == nil {
+		err = apply(n)
+	}
+	if err != nil {
+		return &Error{Column: t.column, Msg: fmt.Sprintf("invalid number %q: %v", t.value, err)}
+	}
+	return nil
+}
+
+func (p *parser) parseWith() error {
+	for {
+		t, err := p.expect(tokenWord)
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(t.value) {
+		case "remaining_count":
+			p.opts.RemainingCount()
+		case "continue":
+			p.opts.WithContinue()
+		default:
+			return unexpected(t, "'remaining_count' or 'continue'")
+		}
+
+		if p.peek().kind != tokenComma {
+			return nil
+		}
+		p.next()
+	}
+}
+
+func isOrderByField(field string) bool {
+	for _, f := range OrderByFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func validateLabelValues(values []token) error {
+	for _, v := range values {
+		if errs := validation.IsValidLabelValue(v.value); len(errs) > 0 {
+			return &Error{Column: v.column, Msg: fmt.Sprintf("invalid value %q: %s", v.value, strings.Join(errs, "; "))}
+		}
+	}
+	return nil
+}
+
+func tokenValues(tokens []token) []string {
+	values := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		values = append(values, t.value)
+	}
+	return values
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/clusterpedia-io/client-go/tools/builder"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParse(t *testing.T) {
+	testCase := []struct {
+		query               string
+		expectLabelSelector string
+		expectFieldSelector string
+		expectLimit         int64
+		expectContinue      string
+	}{
+		{
+			"clusters in (a,b) and namespace=default and name~ngin order by created_at desc limit 10",
+			"internalstorage.clusterpedia.io/fuzzy-name=ngin,search.clusterpedia.io/clusters in (a,b),search.clusterpedia.io/namespaces=default,search.clusterpedia.io/orderby=created_at_desc",
+			"", 10, "",
+		},
+		{
+			"",
+			"", "", 0, "",
+		},
+		{
+			"CLUSTER = a AND Namespaces IN ( 'kube-system' , \"default\" )",
+			"search.clusterpedia.io/clusters=a,search.clusterpedia.io/namespaces in (default,kube-system)",
+			"", 0, "",
+		},
+		{
+			"search.clusterpedia.io/names == nginx and owner_uid=abc-123 and owner_seniority=2",
+			"search.clusterpedia.io/names=nginx,search.clusterpedia.io/owner-seniority=2,search.clusterpedia.io/owner-uid=abc-123",
+			"", 0, "",
+		},
+		{
+			"since=2023-10-01 and before='2023-10-02 12:00:00'",
+			"search.clusterpedia.io/before=1696248000000,search.clusterpedia.io/since=2023-10-01",
+			"", 0, "",
+		},
+		{
+			"label.app.kubernetes.io/name in (nginx, coredns) and field.status.phase=Running",
+			"app.kubernetes.io/name in (coredns,nginx)",
+			"status.phase=Running", 0, "",
+		},
+		{
+			`field.spec.containers[0].image=nginx and field.metadata.annotations['app.io/x']=a and field.data["it.s"] in (a,b)`,
+			"",
+			`data["it.s"] in (a,b),metadata.annotations['app.io/x']=a,spec.containers[0].image=nginx`, 0, "",
+		},
+		{
+			// the brackets are lexed, but the paths aren't supported by clusterpedia, so the field selector is rejected
+			`field.metadata.annotations['app.io/x]']=a and field.data["it's"] in (a,b)`,
+			"", "()", 0, "",
+		},
+		{
+			"order by cluster, name asc, created_at desc offset 20 limit 10 with remaining_count, continue",
+			"search.clusterpedia.io/orderby in (cluster,created_at_desc,name),search.clusterpedia.io/with-continue=true,search.clusterpedia.io/with-remaining-count=true",
+			"", 10, "20",
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.query, func(t *testing.T) {
+			opts, err := Parse(test.query)
+			if err != nil {
+				t.Fatalf("Unexpect error: %v", err)
+			}
+
+			o := opts.Options()
+			if o.LabelSelector != test.expectLabelSelector {
+				t.Errorf("Unexpect label selector: %q, expect: %q", o.LabelSelector, test.expectLabelSelector)
+			}
+			if o.FieldSelector != test.expectFieldSelector {
+				t.Errorf("Unexpect field selector: %q, expect: %q", o.FieldSelector, test.expectFieldSelector)
+			}
+			if o.Limit != test.expectLimit {
+				t.Errorf("Unexpect limit: %d, expect: %d", o.Limit, test.expectLimit)
+			}
+			if o.Continue != test.expectContinue {
+				t.Errorf("Unexpect continue: %q, expect: %q", o.Continue, test.expectContinue)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	testCase := []struct {
+		query        string
+		expectColumn int
+		expectMsg    string
+	}{
+		{"clusters in (a,b", 17, "unexpected end of query, expect ',' or ')'"},
+		{"cluster=a and", 14, "unexpected end of query, expect key"},
+		{"cluster=a or namespace=b", 11, "unexpected 'or'"},
+		{"cluster=a and kind=Pod", 15, `unknown key "kind"`},
+		{"search.clusterpedia.io/unknown=a", 1, `unsupported search label "search.clusterpedia.io/unknown"`},
+		{"search.clusterpedia.io/limit=1", 1, "must be set by the 'limit' clause"},
+		{"namespace~default", 10, "'~' is only supported by name"},
+		{"owner_uid in (a,b)", 11, "owner_uid only accepts a single value"},
+		{"owner_name=a and owner_name=b", 18, "duplicate condition on owner_name"},
+		{"label.app in (a,b) and label.app=c", 24, "duplicate condition on label.app, use 'in' to match any of the values"},
+		{"cluster=a and clusters=b", 15, "duplicate condition on clusters"},
+		{"field.status.phase=Running and field.status.phase=Pending", 32, "duplicate condition on field.status.phase"},
+		{"owner_seniority=0", 17, "owner seniority must be a positive integer"},
+		{"since=yesterday", 7, "invalid datetime"},
+		{"cluster='a b'", 9, `invalid value "a b"`},
+		{"label.-app=a", 1, `invalid label key "-app"`},
+		{"name='nginx", 6, "unterminated string"},
+		{"name=nginx;", 11, "unexpected character ';'"},
+		{"field.spec.containers[0=nginx", 22, "unterminated bracket"},
+		{"order by age", 10, `unsupported order by field "age"`},
+		{"limit 10 limit 20", 10, "duplicate limit clause"},
+		{"limit -1", 7, "must not be negative"},
+		{"limit 0", 7, "limit must be positive"},
+		{"with everything", 6, "expect 'remaining_count' or 'continue'"},
+		{"limit 10 and cluster=a", 10, "unexpected 'and'"},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.query, func(t *testing.T) {
+			_, err := Parse(test.query)
+
+			var queryErr *Error
+			if !errors.As(err, &queryErr) {
+				t.Fatalf("Expect query error, got: %v", err)
+			}
+			if queryErr.Column != test.expectColumn {
+				t.Errorf("Unexpect column: %d, expect: %d, error: %v", queryErr.Column, test.expectColumn, err)
+			}
+			if !strings.Contains(queryErr.Msg, test.expectMsg) {
+				t.Errorf("Unexpect error: %q, expect: %q", queryErr.Msg, test.expectMsg)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	testCase := []struct {
+		opts   builder.ListOptionsInterface
+		expect string
+	}{
+		{
+			builder.ListOptionsBuilder(),
+			"",
+		},
+		{
+			builder.ListOptionsBuilder().Clusters("a", "b").Namespaces("default").FuzzyNames("ngin").
+				OrderBy("created_at", true).Limit(10),
+			"clusters in (a,b) and namespace=default and name~ngin order by created_at desc limit 10",
+		},
+		{
+			builder.ListOptionsBuilder().Names("nginx").OwnerName("nginx-6b7f675859").OwnerSeniority(1).
+				OwnerGroupResource(schema.GroupResource{Group: "apps", Resource: "replicasets"}).
+				LabelSelector("app.kubernetes.io/name", []string{"nginx"}).
+				FieldSelector("status.phase", []string{"Running", "Pending"}),
+			"name=nginx and owner_name=nginx-6b7f675859 and owner_gr=replicasets.apps and owner_seniority=1 and label.app.kubernetes.io/name=nginx and field.status.phase in (Pending,Running)",
+		},
+		{
+			builder.ListOptionsBuilder().
+				FieldSelector(builder.NewFieldPath("spec", "containers").Index(0).Child("image").String(), []string{"nginx"}).
+				FieldSelector(builder.NewFieldPath("metadata", "annotations", "app.io/x").String(), []string{"a", "b"}),
+			"field.metadata.annotations['app.io/x'] in (a,b) and field.spec.containers[0].image=nginx",
+		},
+		{
+			builder.ListOptionsBuilder().Namespaces("limit").Offset(20).Limit(10).RemainingCount().WithContinue(),
+			"namespace='limit' limit 10 offset 20 with continue, remaining_count",
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.expect, func(t *testing.T) {
+			opts := test.opts.Options()
+			query, err := Format(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query != test.expect {
+				t.Errorf("Unexpect query: %q, expect: %q", query, test.expect)
+			}
+
+			parsed, err := Parse(query)
+			if err != nil {
+				t.Fatalf("Failed to parse the formatted query: %v", err)
+			}
+			if o := parsed.Options(); o.LabelSelector != opts.LabelSelector || o.FieldSelector != opts.FieldSelector ||
+				o.Limit != opts.Limit || o.Continue != opts.Continue {
+				t.Errorf("Unexpect round trip options: %+v, expect: %+v", o, opts)
+			}
+		})
+	}
+
+	if _, err := Format(metav1.ListOptions{LabelSelector: "app!=nginx"}); err == nil {
+		t.Errorf("Expect error for the '!=' operator")
+	}
+	if _, err := Format(metav1.ListOptions{LabelSelector: "app in (a,b),app=c"}); err == nil {
+		t.Errorf("Expect error for the label required more than once")
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing creates the OpenTelemetry spans of the requests sent to clusterpedia,
+// the spans are created by wrapping the transport of the clients.
+package tracing
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+
+	"github.com/clusterpedia-io/client-go/client"
+	"github.com/clusterpedia-io/client-go/constants"
+	"github.com/clusterpedia-io/client-go/pkg/requestinfo"
+	"github.com/clusterpedia-io/client-go/retry"
+)
+
+// TracerName is the name of the tracer of the spans.
+const TracerName = "github.com/clusterpedia-io/client-go/tracing"
+
+// The attributes of the spans, they are taken from the request built by the builder options.
+const (
+	VerbKey       = attribute.Key("clusterpedia.verb")
+	ResourceKey   = attribute.Key("clusterpedia.resource")
+	CollectionKey = attribute.Key("clusterpedia.collection")
+	ClustersKey   = attribute.Key("clusterpedia.clusters")
+	NamespacesKey = attribute.Key("clusterpedia.namespaces")
+	LimitKey      = attribute.Key("clusterpedia.limit")
+	OffsetKey     = attribute.Key("clusterpedia.offset")
+	OrderByKey    = attribute.Key("clusterpedia.orderby")
+	// ItemsKey is the number of the items of the list response or the fetched collection resource.
+	ItemsKey = attribute.Key("clusterpedia.items")
+	// AttemptKey is the attempt of the request retried by the retry policy of the clients, see retry.Attempt.
+	AttemptKey = attribute.Key("clusterpedia.attempt")
+)
+
+// Tracing creates the spans of the requests and propagates the trace context to clusterpedia.
+type Tracing struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// New creates the tracing with the tracer provider and the propagator,
+// the global tracer provider and propagator of otel are used if they are nil.
+func New(provider trace.TracerProvider, propagator propagation.TextMapPropagator) *Tracing {
+	return &Tracing{provider: provider, propagator: propagator}
+}
+
+// Option returns the client option which traces the requests of the clients,
+// it can be passed to the constructors of client, dynamic, customclient and clusterpediaclient.
+func (t *Tracing) Option() client.Option {
+	return client.WithWrapTransport(t.WrapTransport)
+}
+
+// WrapTransport returns a round tripper which traces the requests sent by rt,
+// it can be used as the rest.Config.WrapTransport.
+func (t *Tracing) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &roundTripper{delegate: rt, tracing: t}
+}
+
+func (t *Tracing) tracer() trace.Tracer {
+	provider := t.provider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(TracerName)
+}
+
+func (t *Tracing) textMapPropagator() propagation.TextMapPropagator {
+	if t.propagator == nil {
+		return otel.GetTextMapPropagator()
+	}
+	return t.propagator
+}
+
+type roundTripper struct {
+	delegate http.RoundTripper
+	tracing  *Tracing
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	info := requestinfo.New(req)
+	ctx, span := rt.tracing.tracer().Start(req.Context(), "clusterpedia "+info.Verb+" "+info.Resource,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes(req, info)...))
+
+	req = utilnet.CloneRequest(req.WithContext(ctx))
+	rt.tracing.textMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := rt.delegate.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return resp, err
+	}
+
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+	if code, _ := semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(resp.StatusCode, trace.SpanKindClient); code == codes.Error {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	resp.Body = requestinfo.NewBody(info, resp, func(_ int64, items int, err error) {
+		if items >= 0 {
+			span.SetAttributes(ItemsKey.Int(items))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	})
+	return resp, nil
+}
+
+func (rt *roundTripper) WrappedRoundTripper() http.RoundTripper {
+	return rt.delegate
+}
+
+func attributes(req *http.Request, info *requestinfo.Info) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPMethodKey.String(req.Method),
+		semconv.HTTPURLKey.String(req.URL.Redacted()),
+		VerbKey.String(info.Verb),
+		ResourceKey.String(info.Resource),
+	}
+	if info.Collection != "" {
+		attrs = append(attrs, CollectionKey.String(info.Collection))
+	}
+	if attempt := retry.Attempt(req.Context()); attempt > 0 {
+		attrs = append(attrs, AttemptKey.Int(attempt))
+	}
+	if clusters := info.Clusters(); len(clusters) > 0 {
+		attrs = append(attrs, ClustersKey.StringSlice(clusters))
+	}
+	if namespaces := info.Namespaces(); len(namespaces) > 0 {
+		attrs = append(attrs, NamespacesKey.StringSlice(namespaces))
+	}
+	if orderBy := info.SearchLabels[constants.SearchLabelOrderBy]; len(orderBy) > 0 {
+		attrs = append(attrs, OrderByKey.StringSlice(orderBy))
+	}
+
+	limit, offset := info.Limit, info.Continue
+	if values := info.SearchLabels[constants.SearchLabelLimit]; limit == 0 && len(values) == 1 {
+		limit, _ = strconv.ParseInt(values[0], 10, 64)
+	}
+	if values := info.SearchLabels[constants.SearchLabelOffset]; offset == "" && len(values) == 1 {
+		offset = values[0]
+	}
+	if limit > 0 {
+		attrs = append(attrs, LimitKey.Int64(limit))
+	}
+	// the continue token which isn't an offset is not recorded
+	if offset, err := strconv.ParseInt(offset, 10, 64); err == nil {
+		attrs = append(attrs, OffsetKey.Int64(offset))
+	}
+	return attrs
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2021 clusterpedia Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+
+	"github.com/clusterpedia-io/client-go/client"
+	"github.com/clusterpedia-io/client-go/clusterpediaclient"
+	"github.com/clusterpedia-io/client-go/customclient"
+	"github.com/clusterpedia-io/client-go/dynamic"
+	"github.com/clusterpedia-io/client-go/tools/builder"
+	"github.com/clusterpedia-io/client-go/tools/clusterpediatest"
+)
+
+func newTracing() (*Tracing, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return New(provider, propagation.TraceContext{}), exporter
+}
+
+// headerRecorder records the traceparent headers of the requests received by the handler.
+type headerRecorder struct {
+	handler http.Handler
+
+	lock         sync.Mutex
+	traceparents []string
+}
+
+func (r *headerRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	r.traceparents = append(r.traceparents, req.Header.Get("traceparent"))
+	r.lock.Unlock()
+	r.handler.ServeHTTP(w, req)
+}
+
+func TestTracing(t *testing.T) {
+	fake := clusterpediatest.NewServer()
+	if err := fake.LoadFile("../tools/clusterpediatest/testdata/resources.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	recorder := &headerRecorder{handler: fake}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+	config := &rest.Config{Host: server.URL}
+
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	listOptions := builder.ListOptionsBuilder().Clusters("cluster-1", "cluster-2").Namespaces("default").
+		OrderBy("name").Limit(2).Offset(1).Options()
+
+	listAttributes := []attribute.KeyValue{
+		VerbKey.String("list"),
+		ResourceKey.String("apps/v1/deployments"),
+		ClustersKey.StringSlice([]string{"cluster-1", "cluster-2"}),
+		NamespacesKey.StringSlice([]string{"default"}),
+		OrderByKey.StringSlice([]string{"name"}),
+		LimitKey.Int64(2),
+		OffsetKey.Int64(1),
+		ItemsKey.Int(2),
+	}
+	testCase := []struct {
+		name         string
+		request      func(opts ...client.Option) error
+		expectName   string
+		expectAttrs  []attribute.KeyValue
+		expectAbsent []attribute.Key
+	}{
+		{
+			"kubernetes",
+			func(opts ...client.Option) error {
+				kubeClient, err := client.NewForConfig(config, opts...)
+				if err != nil {
+					return err
+				}
+				_, err = kubeClient.AppsV1().Deployments("").List(context.TODO(), listOptions)
+				return err
+			},
+			"clusterpedia list apps/v1/deployments",
+			listAttributes,
+			[]attribute.Key{CollectionKey},
+		},
+		{
+			"customclient",
+			func(opts ...client.Option) error {
+				c, err := customclient.NewForConfig(config, opts...)
+				if err != nil {
+					return err
+				}
+				return c.Resource(deployments).List(context.TODO(), listOptions, nil, &appsv1.DeploymentList{})
+			},
+			"clusterpedia list apps/v1/deployments",
+			listAttributes,
+			[]attribute.Key{CollectionKey},
+		},
+		{
+			"dynamic get of cluster path",
+			func(opts ...client.Option) error {
+				dc, err := dynamic.NewClusterForConfig(config, "cluster-1", opts...)
+				if err != nil {
+					return err
+				}
+				_, err = dc.Resource(deployments).Namespace("default").Get(context.TODO(), "nginx", metav1.GetOptions{})
+				return err
+			},
+			"clusterpedia get apps/v1/deployments",
+			[]attribute.KeyValue{
+				VerbKey.String("get"),
+				ClustersKey.StringSlice([]string{"cluster-1"}),
+				NamespacesKey.StringSlice([]string{"default"}),
+			},
+			[]attribute.Key{ItemsKey, LimitKey, OffsetKey},
+		},
+		{
+			"clusterpediaclient fetch",
+			func(opts ...client.Option) error {
+				cc, err := clusterpediaclient.NewForConfig(config, opts...)
+				if err != nil {
+					return err
+				}
+				_, err = cc.PediaClusterV1beta1().CollectionResource().Fetch(context.TODO(), "workloads",
+					builder.ListOptionsBuilder().Clusters("cluster-2").Options(), nil)
+				return err
+			},
+			"clusterpedia get collectionresources/workloads",
+			[]attribute.KeyValue{
+				CollectionKey.String("workloads"),
+				ClustersKey.StringSlice([]string{"cluster-2"}),
+				ItemsKey.Int(2),
+			},
+			[]attribute.Key{NamespacesKey, OrderByKey},
+		},
+	}
+
+	for _, test := range testCase {
+		t.Run(test.name, func(t *testing.T) {
+			tracing, exporter := newTracing()
+			recorder.traceparents = nil
+			if err := test.request(tracing.Option()); err != nil {
+				t.Fatal(err)
+			}
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("Unexpect spans: %d", len(spans))
+			}
+			span := spans[0]
+			if span.Name != test.expectName || span.SpanKind.String() != "client" || span.Status.Code == codes.Error {
+				t.Errorf("Unexpect span: %s, kind: %s, status: %v", span.Name, span.SpanKind, span.Status)
+			}
+
+			attrs := attribute.NewSet(span.Attributes...)
+			for _, expect := range append(test.expectAttrs, attribute.Int("http.status_code", http.StatusOK)) {
+				if value, ok := attrs.Value(expect.Key); !ok || !reflect.DeepEqual(value, expect.Value) {
+					t.Errorf("Unexpect attribute %s: %v, expect: %v", expect.Key, value.Emit(), expect.Value.Emit())
+				}
+			}
+			for _, key := range test.expectAbsent {
+				if attrs.HasValue(key) {
+					t.Errorf("Unexpect attribute %s", key)
+				}
+			}
+
+			if len(recorder.traceparents) != 1 || !strings.Contains(recorder.traceparents[0], span.SpanContext.TraceID().String()) {
+				t.Errorf("Unexpect traceparent headers: %v, trace id: %s", recorder.traceparents, span.SpanContext.TraceID())
+			}
+		})
+	}
+}
+
+func TestTracingParent(t *testing.T) {
+	server := clusterpediatest.NewTestServer(t, "../tools/clusterpediatest/testdata/resources.yaml")
+	tracing, exporter := newTracing()
+
+	kubeClient, err := client.NewForConfig(server.RESTConfig(), tracing.Option())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, parent := tracing.tracer().Start(context.Background(), "parent")
+	if _, err := kubeClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Unexpect spans: %d", len(spans))
+	}
+	if spans[0].Parent.SpanID() != parent.SpanContext().SpanID() || spans[0].SpanContext.TraceID() != parent.SpanContext().TraceID() {
+		t.Errorf("Expect the span of the request is the child of the parent span")
+	}
+}
+
+func TestTracingErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "storage unavailable", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	tracing, exporter := newTracing()
+	cc, err := clusterpediaclient.NewForConfig(&rest.Config{Host: server.URL}, tracing.Option())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cc.PediaClusterV1beta1().CollectionResource().Fetch(context.TODO(), "workloads", metav1.ListOptions{}, nil); err == nil {
+		t.Fatal("Expect error")
+	}
+	server.Close()
+	if _, err := cc.PediaClusterV1beta1().CollectionResource().Fetch(context.TODO(), "workloads", metav1.ListOptions{}, nil); err == nil {
+		t.Fatal("Expect error")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Unexpect spans: %d", len(spans))
+	}
+	if spans[0].Status.Code != codes.Error || spans[0].Status.Description != http.StatusText(http.StatusInternalServerError) {
+		t.Errorf("Unexpect status of the 500 response: %+v", spans[0].Status)
+	}
+	if spans[1].Status.Code != codes.Error || len(spans[1].Events) != 1 || spans[1].Events[0].Name != "exception" {
+		t.Errorf("Expect the transport error is recorded, status: %+v, events: %+v", spans[1].Status, spans[1].Events)
+	}
+}